@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"finger/token"
+	"math/big"
 	"strings"
 )
 
@@ -72,6 +73,7 @@ type LetStatement struct {
 	Token token.Token // token.LET词法单元
 	Name *Identifier
 	Value Expression
+	Doc string // 紧挨在语句之前的文档注释
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -175,6 +177,42 @@ func (il *IntegerLiteral) String() string {
 }
 	
 
+/*
+	浮点数字面量
+*/
+type FloatLiteral struct {
+	Token token.Token // token.FLOAT词法单元
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
+/*
+	大整数字面量
+*/
+type BigIntLiteral struct {
+	Token token.Token // token.BIGINT词法单元
+	Value *big.Int
+}
+
+func (bl *BigIntLiteral) expressionNode() {}
+
+func (bl *BigIntLiteral) TokenLiteral() string {
+	return bl.Token.Literal
+}
+
+func (bl *BigIntLiteral) String() string {
+	return bl.Token.Literal
+}
+
 /*
 	前缀表达式
 */
@@ -195,6 +233,10 @@ func (pe *PrefixExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(pe.Operator)
+	// typeof 等关键字运算符与操作数之间需要空格
+	if pe.Token.Type == token.TYPEOF {
+		out.WriteString(" ")
+	}
 	out.WriteString(pe.Right.String())
 	out.WriteString(")")
 	
@@ -249,6 +291,40 @@ func (b *Boolean) String() string {
 }
 
 
+/*
+	null字面量
+*/
+type NullLiteral struct {
+	Token token.Token // token.NULL词法单元
+}
+
+func (nl *NullLiteral) expressionNode() {}
+
+func (nl *NullLiteral) TokenLiteral() string {
+	return nl.Token.Literal
+}
+
+func (nl *NullLiteral) String() string {
+	return nl.Token.Literal
+}
+
+/*
+	undefined字面量
+*/
+type UndefinedLiteral struct {
+	Token token.Token // token.UNDEFINED词法单元
+}
+
+func (ul *UndefinedLiteral) expressionNode() {}
+
+func (ul *UndefinedLiteral) TokenLiteral() string {
+	return ul.Token.Literal
+}
+
+func (ul *UndefinedLiteral) String() string {
+	return ul.Token.Literal
+}
+
 /*
 	if语句
 */
@@ -313,6 +389,9 @@ type FunctionLiteral struct {
 	Token token.Token // token.FUNCTION词法单元
 	Parameters []*Identifier
 	Body *BlockStatement
+	Doc string // 函数的文档注释
+	Generator bool // 是否是生成器函数 fn*(...) {...}
+	Async bool // 是否是async函数 async fn(...) {...}
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
@@ -330,7 +409,13 @@ func (fl *FunctionLiteral) String() string {
 		params = append(params, p.String())
 	}
 
+	if fl.Async {
+		out.WriteString("async ")
+	}
 	out.WriteString(fl.TokenLiteral())
+	if fl.Generator {
+		out.WriteString("*")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
@@ -388,6 +473,60 @@ func (sl *StringLiteral) String() string {
 	return sl.Token.Literal
 }
 
+/*
+	模板字符串 `a${x}b`
+	Quasis比Expressions多一个, 求值时依次交替拼接
+	Raws与Quasis一一对应, 保存未处理转义的原始文本
+*/
+type TemplateLiteral struct {
+	Token token.Token // token.TEMPLATE或token.TEMPLATE_HEAD词法单元
+	Quasis []string // 插值之间的文本片段
+	Raws []string // 文本片段的原始文本
+	Expressions []Expression
+}
+
+func (tl *TemplateLiteral) expressionNode() {}
+
+func (tl *TemplateLiteral) TokenLiteral() string {
+	return tl.Token.Literal
+}
+
+func (tl *TemplateLiteral) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("`")
+	for i, raw := range tl.Raws {
+		out.WriteString(raw)
+		if i < len(tl.Expressions) {
+			out.WriteString("${")
+			out.WriteString(tl.Expressions[i].String())
+			out.WriteString("}")
+		}
+	}
+	out.WriteString("`")
+
+	return out.String()
+}
+
+/*
+	带标签的模板字符串 tag`a${x}b`
+*/
+type TaggedTemplateExpression struct {
+	Token token.Token // 模板字符串的第一个词法单元
+	Tag Expression
+	Template *TemplateLiteral
+}
+
+func (tt *TaggedTemplateExpression) expressionNode() {}
+
+func (tt *TaggedTemplateExpression) TokenLiteral() string {
+	return tt.Token.Literal
+}
+
+func (tt *TaggedTemplateExpression) String() string {
+	return tt.Tag.String() + tt.Template.String()
+}
+
 type ArrayLiteral struct {
 	Token token.Token // '['词法单元
 	Elements []Expression
@@ -440,9 +579,62 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+/*
+	哈希表属性的种类
+*/
+type PropertyKind int
+
+const (
+	PropertyKeyValue  PropertyKind = iota // key: value
+	PropertyShorthand                     // {name} 等价于 {name: name}
+	PropertyMethod                        // {greet() {...}}
+	PropertySpread                        // {...other}
+)
+
+/*
+	哈希表字面量中的一个属性
+*/
+type HashProperty struct {
+	Kind     PropertyKind
+	Computed bool       // 计算属性名 {[expr]: value}
+	Key      Expression // 展开属性时为nil
+	Value    Expression
+}
+
+func (hp *HashProperty) String() string {
+	if hp.Kind == PropertySpread {
+		return "..." + hp.Value.String()
+	}
+
+	key := hp.Key.String()
+	if hp.Computed {
+		key = "[" + key + "]"
+	}
+
+	switch hp.Kind {
+	case PropertyShorthand:
+		return key
+	case PropertyMethod:
+		fn := hp.Value.(*FunctionLiteral)
+		params := []string{}
+		for _, p := range fn.Parameters {
+			params = append(params, p.String())
+		}
+		if fn.Generator {
+			key = "*" + key
+		}
+		if fn.Async {
+			key = "async " + key
+		}
+		return key + "(" + strings.Join(params, ", ") + ") {" + fn.Body.String() + "}"
+	default:
+		return key + ":" + hp.Value.String()
+	}
+}
+
 type HashLiteral struct {
 	Token token.Token // token.LBRACE词法单元
-	Pairs map[Expression]Expression // 键值对
+	Properties []*HashProperty // 按书写顺序排列的属性, 后出现的同名属性覆盖先出现的
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -456,8 +648,8 @@ func (hl *HashLiteral) String() string {
 
 	pairs := []string{}
 
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String() + ":" + value.String())
+	for _, prop := range hl.Properties {
+		pairs = append(pairs, prop.String())
 	}
 
 	out.WriteString("{")
@@ -466,3 +658,382 @@ func (hl *HashLiteral) String() string {
 
 	return out.String()
 }
+
+/*
+	成员访问表达式 object.property
+*/
+type MemberExpression struct {
+	Token token.Token // token.DOT词法单元
+	Object Expression
+	Property *Identifier // 属性名, 可以是关键字
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Property.String() + ")"
+}
+
+/*
+	赋值表达式 target = value
+	赋值目标只能是标识符、成员访问或索引表达式
+*/
+type AssignExpression struct {
+	Token token.Token // token.ASSIGN词法单元
+	Target Expression
+	Value Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+func (ae *AssignExpression) String() string {
+	return "(" + ae.Target.String() + " = " + ae.Value.String() + ")"
+}
+
+type ThisExpression struct {
+	Token token.Token // token.THIS词法单元
+}
+
+func (te *ThisExpression) expressionNode() {}
+
+func (te *ThisExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+
+func (te *ThisExpression) String() string {
+	return te.Token.Literal
+}
+
+/*
+	super只能出现在 super(...) 和 super.method 中
+*/
+type SuperExpression struct {
+	Token token.Token // token.SUPER词法单元
+}
+
+func (se *SuperExpression) expressionNode() {}
+
+func (se *SuperExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+func (se *SuperExpression) String() string {
+	return se.Token.Literal
+}
+
+/*
+	创建实例 new Class(args), 没有参数时可以省略括号
+*/
+type NewExpression struct {
+	Token token.Token // token.NEW词法单元
+	Class Expression
+	Arguments []Expression
+}
+
+func (ne *NewExpression) expressionNode() {}
+
+func (ne *NewExpression) TokenLiteral() string {
+	return ne.Token.Literal
+}
+
+func (ne *NewExpression) String() string {
+	args := []string{}
+	for _, a := range ne.Arguments {
+		args = append(args, a.String())
+	}
+
+	return "new " + ne.Class.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+/*
+	类的成员: 方法或字段
+*/
+type ClassMember struct {
+	Name *Identifier // 计算成员名的Value是 [expr] 形式的源码
+	Key Expression // 计算成员名 [expr] 中的表达式, 普通成员为nil
+	Static bool
+	Method bool // 为true时Value是*FunctionLiteral
+	Value Expression // 字段的初始值, 没有初始值时为nil
+}
+
+func (cm *ClassMember) String() string {
+	var out bytes.Buffer
+
+	if cm.Static {
+		out.WriteString("static ")
+	}
+	if cm.Method && cm.Value.(*FunctionLiteral).Async {
+		out.WriteString("async ")
+	}
+	if cm.Method && cm.Value.(*FunctionLiteral).Generator {
+		out.WriteString("*")
+	}
+	out.WriteString(cm.Name.String())
+
+	if cm.Method {
+		fn := cm.Value.(*FunctionLiteral)
+		params := []string{}
+		for _, p := range fn.Parameters {
+			params = append(params, p.String())
+		}
+		out.WriteString("(" + strings.Join(params, ", ") + ") {" + fn.Body.String() + "}")
+	} else if cm.Value != nil {
+		out.WriteString(" = " + cm.Value.String())
+	}
+
+	return out.String()
+}
+
+/*
+	类字面量 class Name extends Base {...}
+	构造函数单独保存, 不在Members中
+*/
+type ClassLiteral struct {
+	Token token.Token // token.CLASS词法单元
+	Name *Identifier // 匿名类为nil
+	SuperClass Expression // 没有父类时为nil
+	Constructor *FunctionLiteral
+	Members []*ClassMember
+	Doc string // 类的文档注释
+}
+
+func (cl *ClassLiteral) expressionNode() {}
+
+func (cl *ClassLiteral) TokenLiteral() string {
+	return cl.Token.Literal
+}
+
+func (cl *ClassLiteral) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("class ")
+	if cl.Name != nil {
+		out.WriteString(cl.Name.String() + " ")
+	}
+	if cl.SuperClass != nil {
+		out.WriteString("extends " + cl.SuperClass.String() + " ")
+	}
+
+	members := []string{}
+	if cl.Constructor != nil {
+		constructor := &ClassMember{
+			Name: &Identifier{Token: cl.Token, Value: "constructor"},
+			Method: true,
+			Value: cl.Constructor,
+		}
+		members = append(members, constructor.String())
+	}
+	for _, m := range cl.Members {
+		members = append(members, m.String())
+	}
+
+	out.WriteString("{" + strings.Join(members, "; ") + "}")
+
+	return out.String()
+}
+
+/*
+	类声明语句 class Name {...}, 把类绑定到Name上
+*/
+type ClassStatement struct {
+	Token token.Token // token.CLASS词法单元
+	Class *ClassLiteral
+}
+
+func (cs *ClassStatement) statementNode() {}
+
+func (cs *ClassStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+func (cs *ClassStatement) String() string {
+	return cs.Class.String()
+}
+
+/*
+	while循环 while (condition) {...}
+*/
+type WhileStatement struct {
+	Token token.Token // token.WHILE词法单元
+	Condition Expression
+	Body *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+
+func (ws *WhileStatement) TokenLiteral() string {
+	return ws.Token.Literal
+}
+
+func (ws *WhileStatement) String() string {
+	return "while (" + ws.Condition.String() + ") {" + ws.Body.String() + "}"
+}
+
+/*
+	for...of循环 for (let name of iterable) {...}
+	省略let时赋值给已经声明的变量
+*/
+type ForOfStatement struct {
+	Token token.Token // token.FOR词法单元
+	Declare bool // 是否用let在每次迭代中声明新的变量
+	Name *Identifier
+	Iterable Expression
+	Body *BlockStatement
+}
+
+func (fs *ForOfStatement) statementNode() {}
+
+func (fs *ForOfStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+
+func (fs *ForOfStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	if fs.Declare {
+		out.WriteString("let ")
+	}
+	out.WriteString(fs.Name.String() + " of " + fs.Iterable.String() + ") {")
+	out.WriteString(fs.Body.String() + "}")
+
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token token.Token // token.BREAK词法单元
+}
+
+func (bs *BreakStatement) statementNode() {}
+
+func (bs *BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+
+func (bs *BreakStatement) String() string {
+	return bs.Token.Literal + ";"
+}
+
+type ContinueStatement struct {
+	Token token.Token // token.CONTINUE词法单元
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+func (cs *ContinueStatement) String() string {
+	return cs.Token.Literal + ";"
+}
+
+/*
+	throw语句, 可以抛出任何值
+*/
+type ThrowStatement struct {
+	Token token.Token // token.THROW词法单元
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+func (ts *ThrowStatement) String() string {
+	return "throw " + ts.Value.String() + ";"
+}
+
+/*
+	try语句 try {...} catch (e) {...} finally {...}
+	catch和finally至少有一个, catch的参数可以省略
+*/
+type TryStatement struct {
+	Token token.Token // token.TRY词法单元
+	Block *BlockStatement
+	Param *Identifier // catch的参数, 没有时为nil
+	Handler *BlockStatement // catch块, 没有时为nil
+	Finalizer *BlockStatement // finally块, 没有时为nil
+}
+
+func (ts *TryStatement) statementNode() {}
+
+func (ts *TryStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try {" + ts.Block.String() + "}")
+	if ts.Handler != nil {
+		out.WriteString(" catch ")
+		if ts.Param != nil {
+			out.WriteString("(" + ts.Param.String() + ") ")
+		}
+		out.WriteString("{" + ts.Handler.String() + "}")
+	}
+	if ts.Finalizer != nil {
+		out.WriteString(" finally {" + ts.Finalizer.String() + "}")
+	}
+
+	return out.String()
+}
+
+/*
+	yield表达式 yield value, yield* iterable
+*/
+type YieldExpression struct {
+	Token token.Token // token.YIELD词法单元
+	Value Expression // 产出的值, 省略时为nil
+	Delegate bool // 是否是 yield*, 即把迭代委托给另一个可迭代对象
+}
+
+func (ye *YieldExpression) expressionNode() {}
+
+func (ye *YieldExpression) TokenLiteral() string {
+	return ye.Token.Literal
+}
+
+func (ye *YieldExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(" + ye.TokenLiteral())
+	if ye.Delegate {
+		out.WriteString("*")
+	}
+	if ye.Value != nil {
+		out.WriteString(" " + ye.Value.String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+/*
+	await表达式 await value
+*/
+type AwaitExpression struct {
+	Token token.Token // token.AWAIT词法单元
+	Value Expression
+}
+
+func (ae *AwaitExpression) expressionNode() {}
+
+func (ae *AwaitExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+func (ae *AwaitExpression) String() string {
+	return "(" + ae.TokenLiteral() + " " + ae.Value.String() + ")"
+}
@@ -3,8 +3,21 @@ package evaluator
 import (
 	"finger/object"
 	"fmt"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
+/*
+	内置的全局对象, 如 Symbol
+	与内置函数一样, 可以被同名的变量遮蔽
+*/
+var globals = map[string]object.Object{}
+
 var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
@@ -14,8 +27,8 @@ var builtins = map[string]*object.Builtin{
 
 			switch arg := args[0].(type) {
 			case *object.String:
-				// 调用Go的len方法
-				return &object.Integer{Value: int64(len(arg.Value))}
+				// 按字符(Unicode码点)计数, 字节数使用byteLength
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			default:
@@ -23,6 +36,51 @@ var builtins = map[string]*object.Builtin{
 			}
 		},
 	},
+	// 字符串的UTF-8字节数
+	"byteLength": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `byteLength` must be STRING, got %s", args[0].Type())
+			}
+			return &object.Integer{Value: int64(len(str.Value))}
+		},
+	},
+	// 按字符截取子串 substr(s, start, end?), 负数索引从末尾开始计算
+	"substr": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `substr` must be STRING, got %s", args[0].Type())
+			}
+
+			runes := []rune(str.Value)
+			length := int64(len(runes))
+			bounds := []int64{0, length}
+
+			for i, arg := range args[1:] {
+				if isNullish(arg) {
+					continue
+				}
+				index, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("index argument to `substr` must be INTEGER, got %s", arg.Type())
+				}
+				bounds[i] = clampIndex(index.Value, length)
+			}
+
+			if bounds[0] >= bounds[1] {
+				return &object.String{Value: ""}
+			}
+			return &object.String{Value: string(runes[bounds[0]:bounds[1]])}
+		},
+	},
 	"first": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
@@ -90,6 +148,128 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
+	// 显式转换为大整数
+	"BigInt": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.BigInt:
+				return arg
+			case *object.Integer:
+				return &object.BigInt{Value: big.NewInt(arg.Value)}
+			case *object.Float:
+				if math.IsInf(arg.Value, 0) || math.IsNaN(arg.Value) || arg.Value != math.Trunc(arg.Value) {
+					return newError("cannot convert %s to BIGINT: not an integer", arg.Inspect())
+				}
+				value, _ := big.NewFloat(arg.Value).Int(nil)
+				return &object.BigInt{Value: value}
+			case *object.String:
+				value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 10)
+				if !ok {
+					return newError("cannot convert %q to BIGINT", arg.Value)
+				}
+				return &object.BigInt{Value: value}
+			default:
+				return newError("argument to `BigInt` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	// 显式转换为普通数字, 超出int64范围的大整数转换为(可能损失精度的)浮点数
+	"Number": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.Float:
+				return arg
+			case *object.BigInt:
+				if arg.Value.IsInt64() {
+					return &object.Integer{Value: arg.Value.Int64()}
+				}
+				value, _ := new(big.Float).SetInt(arg.Value).Float64()
+				return &object.Float{Value: value}
+			default:
+				return newError("argument to `Number` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	// 结构相等比较
+	"equals": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			return nativeBoolToBooleanObject(object.DeepEqual(args[0], args[1]))
+		},
+	},
+	// 递归冻结数组和哈希表, 返回对象本身
+	"freeze": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			object.Freeze(args[0])
+			return args[0]
+		},
+	},
+	"isFrozen": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Array:
+				return nativeBoolToBooleanObject(arg.Frozen)
+			case *object.Hash:
+				return nativeBoolToBooleanObject(arg.Frozen)
+			case *object.Instance:
+				return nativeBoolToBooleanObject(arg.Fields.Frozen)
+			default:
+				// 其他值本身不可变
+				return TRUE
+			}
+		},
+	},
+	// 比较两个字符串(或两个数字), 返回-1, 0或1
+	// 指定locale时按该语言的排序规则比较, 如 compare("ä", "b", "sv") 为1; 不支持的locale是错误
+	"compare": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+			if len(args) == 3 && args[2].Type() != object.STRING_OBJ {
+				return newError("locale argument to `compare` must be STRING, got %s", args[2].Type())
+			}
+
+			left, right := args[0], args[1]
+			switch {
+			case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+				leftVal := left.(*object.String).Value
+				rightVal := right.(*object.String).Value
+				if len(args) == 3 {
+					cmp, err := compareInLocale(args[2].(*object.String).Value, leftVal, rightVal)
+					if err != nil {
+						return err
+					}
+					return &object.Integer{Value: int64(cmp)}
+				}
+				return &object.Integer{Value: int64(strings.Compare(leftVal, rightVal))}
+			case isNumeric(left) && isNumeric(right):
+				cmp := compareNumbers(left, right)
+				if cmp == 2 {
+					return newError("cannot compare NaN")
+				}
+				return &object.Integer{Value: int64(cmp)}
+			default:
+				return newError("cannot compare %s and %s", left.Type(), right.Type())
+			}
+		},
+	},
 	"print": {
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
@@ -98,4 +278,133 @@ var builtins = map[string]*object.Builtin{
 			return NULL
 		},
 	},
+	// 创建以proto为原型的哈希表 create(proto, properties?), proto可以是null
+	"create": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			hash := object.NewHash()
+			if err := setPrototype(hash, args[0]); err != nil {
+				return err
+			}
+
+			if len(args) == 2 {
+				props, ok := args[1].(*object.Hash)
+				if !ok {
+					return newError("properties argument to `create` must be HASH, got %s", args[1].Type())
+				}
+				for _, pair := range props.Pairs() {
+					hash.Set(pair.Key.(object.Hashable), pair.Value)
+				}
+			}
+
+			return hash
+		},
+	},
+	// 创建错误对象 Error(message, kind?), kind默认为"Error"
+	"Error": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			kind := object.ERROR_KIND
+			if len(args) == 2 {
+				str, ok := args[1].(*object.String)
+				if !ok {
+					return newError("kind argument to `Error` must be STRING, got %s", args[1].Type())
+				}
+				kind = str.Value
+			}
+			return newErrorObject(toDisplayString(args[0]), kind)
+		},
+	},
+	// 返回函数或类的文档注释, 没有文档注释时返回undefined
+	"doc": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if doc, ok := Doc(args[0]); ok {
+				return &object.String{Value: doc}
+			}
+			return UNDEFINED
+		},
+	},
+}
+
+/*
+	返回值的文档注释, 函数、方法和类可以带文档注释
+*/
+func Doc(obj object.Object) (string, bool) {
+	var doc string
+	switch obj := obj.(type) {
+	case *object.Function:
+		doc = obj.Doc
+	case *object.BoundMethod:
+		doc = obj.Fn.Doc
+	case *object.Class:
+		doc = obj.Doc
+	}
+	return doc, doc != ""
+}
+
+/*
+	将可能为负数的索引转换为[0, length]范围内的索引
+*/
+func clampIndex(index, length int64) int64 {
+	if index < 0 {
+		index += length
+	}
+	if index < 0 {
+		return 0
+	}
+	if index > length {
+		return length
+	}
+	return index
+}
+
+var (
+	collatorsMu sync.Mutex // 保护collators; 排序器比较时会修改内部状态, 同样需要持有
+	collators = map[string]*collate.Collator{} // 按locale缓存的排序器
+	collationMatcher = language.NewMatcher(collate.Supported()) // 用于检查locale是否有对应的排序规则
+)
+
+/*
+	按locale的排序规则比较两个字符串
+	定时器和异步函数可能在不同的goroutine中调用, 因此整个比较过程都持有锁
+*/
+func compareInLocale(locale, a, b string) (int, *object.Error) {
+	collatorsMu.Lock()
+	defer collatorsMu.Unlock()
+
+	collator, err := localeCollator(locale)
+	if err != nil {
+		return 0, err
+	}
+	return collator.CompareString(a, b), nil
+}
+
+/*
+	返回locale对应的排序器, 调用方必须持有collatorsMu
+	locale必须是合法的BCP 47语言标签, 并且有对应的排序规则, 如 en、de、sv、zh-Hans
+*/
+func localeCollator(locale string) (*collate.Collator, *object.Error) {
+	if collator, ok := collators[locale]; ok {
+		return collator, nil
+	}
+
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, newRangeError("unsupported locale: %q", locale)
+	}
+	if _, _, confidence := collationMatcher.Match(tag); confidence == language.No {
+		return nil, newRangeError("unsupported locale: %q", locale)
+	}
+
+	collator := collate.New(tag)
+	collators[locale] = collator
+	return collator, nil
 }
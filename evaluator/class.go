@@ -0,0 +1,471 @@
+package evaluator
+
+/*
+	类、实例、成员访问与赋值的求值
+	方法调用时在函数环境中绑定 this 和 super:
+	this 是接收者, super 是定义该方法的类, super.method 从它的父类开始查找
+	方法中定义的普通函数通过闭包共享外层方法的 this
+*/
+
+import (
+	"finger/ast"
+	"finger/object"
+	"finger/token"
+)
+
+/*
+	类字面量求值
+	方法先于静态字段创建, 因此静态字段的初始值可以调用静态方法
+*/
+func evalClassLiteral(node *ast.ClassLiteral, env *object.Environment) object.Object {
+	class := &object.Class{
+		Methods: make(map[string]*object.Function),
+		Statics: make(map[string]object.Object),
+		Env: env,
+		Doc: node.Doc,
+	}
+
+	if node.Name != nil {
+		class.Name = node.Name.Value
+		// 类体中可以通过类名引用类本身
+		class.Env = object.NewEnclosedEnvironment(env)
+		class.Env.Set(class.Name, class)
+	}
+
+	if node.SuperClass != nil {
+		super := Eval(node.SuperClass, env)
+		if isError(super) {
+			return super
+		}
+		parent, ok := super.(*object.Class)
+		if !ok {
+			return withPosition(newTypeError("class extends value is not a class: %s", super.Type()), node.Token)
+		}
+		class.Super = parent
+	}
+
+	if node.Constructor != nil {
+		class.Constructor = newMethod("constructor", node.Constructor, class.Env)
+	}
+
+	for _, member := range node.Members {
+		if !member.Method {
+			if !member.Static {
+				class.Fields = append(class.Fields, member)
+			}
+			continue
+		}
+
+		name, err := memberName(member, class.Env)
+		if err != nil {
+			return err
+		}
+		method := newMethod(name, member.Value.(*ast.FunctionLiteral), class.Env)
+		if member.Static {
+			class.Statics[name] = method
+		} else {
+			class.Methods[name] = method
+		}
+	}
+
+	// 静态字段在类创建时求值, this 是类本身
+	for _, member := range node.Members {
+		if member.Method || !member.Static {
+			continue
+		}
+		value := evalField(member, class, class)
+		if isError(value) {
+			return value
+		}
+		class.Statics[member.Name.Value] = value
+	}
+
+	return class
+}
+
+/*
+	成员名, 计算成员名在类体的环境中求值, 必须是字符串
+*/
+func memberName(member *ast.ClassMember, env *object.Environment) (string, object.Object) {
+	if member.Key == nil {
+		return member.Name.Value, nil
+	}
+
+	key := Eval(member.Key, env)
+	if isError(key) {
+		return "", key
+	}
+	name, ok := key.(*object.String)
+	if !ok {
+		return "", withPosition(newTypeError("class member name must be STRING, got %s", key.Type()), member.Name.Token)
+	}
+
+	return name.Value, nil
+}
+
+func newMethod(name string, fn *ast.FunctionLiteral, env *object.Environment) *object.Function {
+	return &object.Function{Parameters: fn.Parameters, Body: fn.Body, Env: env, Doc: fn.Doc, Name: name, Generator: fn.Generator, Async: fn.Async}
+}
+
+/*
+	求值字段的初始值, 没有初始值的字段为undefined
+*/
+func evalField(field *ast.ClassMember, class *object.Class, this object.Object) object.Object {
+	if field.Value == nil {
+		return UNDEFINED
+	}
+
+	env := object.NewEnclosedEnvironment(class.Env)
+	env.Set("this", this)
+	env.Set("super", class)
+
+	return Eval(field.Value, env)
+}
+
+/*
+	new表达式求值
+*/
+func evalNewExpression(node *ast.NewExpression, env *object.Environment) object.Object {
+	callee := Eval(node.Class, env)
+	if isError(callee) {
+		return callee
+	}
+
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	if hash, ok := callee.(*object.Hash); ok && hash == promiseConstructor {
+		return withPosition(newPromiseFromExecutor(args, node.Token.Pos), node.Token)
+	}
+
+	class, ok := callee.(*object.Class)
+	if !ok {
+		return withPosition(newTypeError("not a class: %s", callee.Type()), node.Token)
+	}
+
+	return withPosition(newInstance(class, args, node.Token.Pos), node.Token)
+}
+
+/*
+	创建实例
+	先从最顶层的父类开始依次初始化各个类声明的字段, 再调用沿继承链找到的第一个构造函数
+	没有构造函数的子类使用父类的构造函数
+*/
+func newInstance(class *object.Class, args []object.Object, callSite token.Position) object.Object {
+	instance := &object.Instance{Class: class, Fields: object.NewHash()}
+
+	if err := initFields(class, instance); err != nil {
+		return err
+	}
+
+	if constructor, home := class.FindConstructor(); constructor != nil {
+		result := applyFunction(&object.BoundMethod{Receiver: instance, Fn: constructor, Home: home}, args, callSite)
+		if isError(result) {
+			return result
+		}
+	}
+
+	return instance
+}
+
+func initFields(class *object.Class, instance *object.Instance) object.Object {
+	if class.Super != nil {
+		if err := initFields(class.Super, instance); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range class.Fields {
+		value := evalField(field, class, instance)
+		if isError(value) {
+			return value
+		}
+		instance.Fields.Set(&object.String{Value: field.Name.Value}, value)
+	}
+
+	return nil
+}
+
+/*
+	this表达式求值
+*/
+func evalThisExpression(node *ast.ThisExpression, env *object.Environment) object.Object {
+	if this, ok := env.Get("this"); ok {
+		return this
+	}
+
+	return withPosition(newReferenceError("'this' is not defined outside of a method"), node.Token)
+}
+
+/*
+	返回方法中的this以及super查找的起点, 即定义该方法的类的父类
+*/
+func superContext(env *object.Environment) (object.Object, *object.Class, *object.Error) {
+	home, ok := env.Get("super")
+	if !ok {
+		return nil, nil, newReferenceError("'super' is not defined outside of a method")
+	}
+
+	class := home.(*object.Class)
+	if class.Super == nil {
+		return nil, nil, newError("'super' used in class %s which has no parent class", class.Name)
+	}
+
+	this, _ := env.Get("this")
+	return this, class.Super, nil
+}
+
+/*
+	super(args) 求值: 在同一个this上调用父类的构造函数
+*/
+func evalSuperCall(node *ast.CallExpression, env *object.Environment) object.Object {
+	this, parent, err := superContext(env)
+	if err != nil {
+		return withPosition(err, node.Token)
+	}
+
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	constructor, home := parent.FindConstructor()
+	if constructor == nil {
+		return UNDEFINED
+	}
+
+	result := applyFunction(&object.BoundMethod{Receiver: this, Fn: constructor, Home: home}, args, node.Token.Pos)
+	if isError(result) {
+		return withPosition(result, node.Token)
+	}
+
+	return UNDEFINED
+}
+
+/*
+	super.name 求值: 从父类开始查找方法, 静态方法中查找父类的静态成员
+*/
+func evalSuperMember(node *ast.MemberExpression, env *object.Environment) object.Object {
+	this, parent, err := superContext(env)
+	if err != nil {
+		return err
+	}
+
+	name := node.Property.Value
+	if _, ok := this.(*object.Class); ok {
+		value, home := parent.FindStatic(name)
+		if value == nil {
+			return UNDEFINED
+		}
+		return bindMethod(this, value, home)
+	}
+
+	method, home := parent.FindMethod(name)
+	if method == nil {
+		return UNDEFINED
+	}
+
+	return &object.BoundMethod{Receiver: this, Fn: method, Home: home}
+}
+
+/*
+	成员访问表达式求值
+*/
+func evalMemberExpression(node *ast.MemberExpression, env *object.Environment) object.Object {
+	if _, ok := node.Object.(*ast.SuperExpression); ok {
+		return withPosition(evalSuperMember(node, env), node.Token)
+	}
+
+	obj := Eval(node.Object, env)
+	if isError(obj) {
+		return obj
+	}
+
+	return withPosition(getProperty(obj, node.Property.Value), node.Token)
+}
+
+/*
+	读取属性, 不存在的属性为undefined
+	实例先查找字段再查找方法; 取出的函数绑定到读取它的对象上
+*/
+func getProperty(obj object.Object, name string) object.Object {
+	key := &object.String{Value: name}
+
+	switch obj := obj.(type) {
+	case *object.Instance:
+		if value, ok := obj.Fields.Get(key); ok {
+			return value
+		}
+		if method, home := obj.Class.FindMethod(name); method != nil {
+			return &object.BoundMethod{Receiver: obj, Fn: method, Home: home}
+		}
+		return UNDEFINED
+	case *object.Class:
+		if value, home := obj.FindStatic(name); value != nil {
+			return bindMethod(obj, value, home)
+		}
+		return UNDEFINED
+	case *object.Generator:
+		return generatorMethod(obj, name)
+	case *object.Iterator:
+		return iteratorMethod(obj, name)
+	case *object.Promise:
+		return promiseMethod(obj, name)
+	case *object.Array:
+		// 模板标签函数收到的文本片段数组的原始文本
+		if name == "raw" && obj.Raw != nil {
+			return obj.Raw
+		}
+		return newTypeError("cannot read property %q of %s", name, obj.Type())
+	case *object.Hash:
+		if name == "__proto__" {
+			return protoOf(obj)
+		}
+		if value, ok := obj.Lookup(key); ok {
+			return bindMethod(obj, value, nil)
+		}
+		return UNDEFINED
+	default:
+		return newTypeError("cannot read property %q of %s", name, obj.Type())
+	}
+}
+
+/*
+	把函数绑定到接收者上, 其他值原样返回
+*/
+func bindMethod(receiver, value object.Object, home *object.Class) object.Object {
+	if fn, ok := value.(*object.Function); ok {
+		return &object.BoundMethod{Receiver: receiver, Fn: fn, Home: home}
+	}
+	return value
+}
+
+/*
+	赋值表达式求值, 先求值赋值目标中的对象和索引, 再求值右侧的值
+	赋值表达式的值是赋给目标的值
+*/
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+		if !env.Assign(target.Value, value) {
+			return withPosition(newReferenceError("identifier not found: "+target.Value), target.Token)
+		}
+		return value
+	case *ast.MemberExpression:
+		if _, ok := target.Object.(*ast.SuperExpression); ok {
+			return withPosition(newTypeError("cannot assign to a property of 'super'"), node.Token)
+		}
+		obj := Eval(target.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+		return withPosition(setProperty(obj, target.Property.Value, value), node.Token)
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+		return withPosition(setIndex(left, index, value), node.Token)
+	default:
+		return withPosition(newError("invalid assignment target"), node.Token)
+	}
+}
+
+/*
+	设置属性, 静态字段赋值时设置在被赋值的类本身上
+	赋值总是设置在对象自身上, 不会修改原型
+*/
+func setProperty(obj object.Object, name string, value object.Object) object.Object {
+	key := &object.String{Value: name}
+
+	switch obj := obj.(type) {
+	case *object.Instance:
+		if obj.Fields.Frozen {
+			return newTypeError("cannot assign to property %q of a frozen object", name)
+		}
+		obj.Fields.Set(key, value)
+	case *object.Class:
+		obj.Statics[name] = value
+	case *object.Hash:
+		if obj.Frozen {
+			return newTypeError("cannot assign to property %q of a frozen hash", name)
+		}
+		if name == "__proto__" {
+			if err := setPrototype(obj, value); err != nil {
+				return err
+			}
+			break
+		}
+		obj.Set(key, value)
+	default:
+		return newTypeError("cannot set property %q of %s", name, obj.Type())
+	}
+
+	return value
+}
+
+/*
+	索引赋值
+	数组索引不能越界, 但可以在末尾追加一个元素
+*/
+func setIndex(left, index, value object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		if left.Frozen {
+			return newTypeError("cannot assign to index of a frozen array")
+		}
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newTypeError("array index must be INTEGER, got %s", index.Type())
+		}
+		length := int64(len(left.Elements))
+		switch {
+		case idx.Value < 0 || idx.Value > length:
+			return newRangeError("index out of range: %d", idx.Value)
+		case idx.Value == length:
+			left.Elements = append(left.Elements, value)
+		default:
+			left.Elements[idx.Value] = value
+		}
+		return value
+	case *object.Hash:
+		if str, ok := index.(*object.String); ok && str.Value == "__proto__" {
+			return setProperty(left, str.Value, value)
+		}
+		if left.Frozen {
+			return newTypeError("cannot assign to key of a frozen hash")
+		}
+		key, ok := object.AsHashable(index)
+		if !ok {
+			return newTypeError("unusable as hash key: %s", index.Type())
+		}
+		left.Set(key, value)
+		return value
+	case *object.Instance, *object.Class:
+		name, ok := index.(*object.String)
+		if !ok {
+			return newTypeError("property name must be STRING, got %s", index.Type())
+		}
+		return setProperty(left, name.Value, value)
+	default:
+		return newTypeError("index assignment not supported: %s", left.Type())
+	}
+}
@@ -0,0 +1,162 @@
+package evaluator
+
+/*
+	循环与异常处理
+	抛出的值和运行时错误都以*object.Error的形式向外传播, 直到被catch捕获
+	break、continue、return和错误统称为提前结束, finally块中的提前结束会覆盖try和catch的结果
+*/
+
+import (
+	"finger/ast"
+	"finger/object"
+	"finger/token"
+)
+
+/*
+	while循环求值
+*/
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := Eval(node.Body, env)
+		switch result.(type) {
+		case *object.Break:
+			return NULL
+		case *object.ReturnValue, *object.Error:
+			return result
+		}
+	}
+}
+
+/*
+	throw语句求值
+*/
+func evalThrowStatement(node *ast.ThrowStatement, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	return thrownError(value, node.Token.Pos)
+}
+
+/*
+	把抛出的值包装成错误, 使它像运行时错误一样向外传播
+	抛出错误对象时沿用其中的message和kind, 其他值未被捕获时显示为 uncaught value
+	Error()创建的错误对象在第一次抛出时记录位置和调用栈
+*/
+func thrownError(value object.Object, pos token.Position) *object.Error {
+	err := &object.Error{Message: "uncaught " + toDisplayString(value), Value: value, Pos: pos, Stack: captureStack()}
+
+	hash, ok := value.(*object.Hash)
+	if !ok {
+		return err
+	}
+
+	if message, ok := hash.Get(&object.String{Value: "message"}); ok && message.Type() == object.STRING_OBJ {
+		err.Message = message.(*object.String).Value
+	}
+	if kind, ok := hash.Get(&object.String{Value: "kind"}); ok && kind.Type() == object.STRING_OBJ {
+		err.Kind = kind.(*object.String).Value
+	}
+	if line, ok := hash.Get(&object.String{Value: "line"}); ok && line == NULL && !hash.Frozen {
+		setErrorLocation(hash, err)
+	}
+
+	return err
+}
+
+/*
+	try语句求值
+*/
+func evalTryStatement(node *ast.TryStatement, env *object.Environment) object.Object {
+	result := Eval(node.Block, env)
+
+	if err, ok := result.(*object.Error); ok && !err.Uncatchable && node.Handler != nil {
+		handlerEnv := object.NewEnclosedEnvironment(env)
+		if node.Param != nil {
+			handlerEnv.Set(node.Param.Value, errorValue(err))
+		}
+		result = Eval(node.Handler, handlerEnv)
+	}
+
+	if node.Finalizer != nil {
+		final := Eval(node.Finalizer, env)
+		if isAbrupt(final) {
+			return final
+		}
+	}
+
+	return result
+}
+
+/*
+	检查结果是否会提前结束所在的语句块
+*/
+func isAbrupt(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.ReturnValue, *object.Error, *object.Break, *object.Continue:
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+	catch捕获到的值: 抛出的值原样返回, 运行时错误转换为错误对象
+*/
+func errorValue(err *object.Error) object.Object {
+	if err.Value != nil {
+		return err.Value
+	}
+
+	hash := newErrorObject(err.Message, err.Kind)
+	setErrorLocation(hash, err)
+
+	return hash
+}
+
+/*
+	创建错误对象, 即包含message、kind、line、column和stack的哈希表
+	位置和调用栈在抛出之前为null
+*/
+func newErrorObject(message, kind string) *object.Hash {
+	if kind == "" {
+		kind = object.ERROR_KIND
+	}
+
+	hash := object.NewHash()
+	hash.Set(&object.String{Value: "message"}, &object.String{Value: message})
+	hash.Set(&object.String{Value: "kind"}, &object.String{Value: kind})
+	hash.Set(&object.String{Value: "line"}, NULL)
+	hash.Set(&object.String{Value: "column"}, NULL)
+	hash.Set(&object.String{Value: "stack"}, NULL)
+
+	return hash
+}
+
+/*
+	把错误的位置和调用栈写入错误对象
+	stack的第一行是 "kind: message", 之后每行是调用栈中的一帧
+*/
+func setErrorLocation(hash *object.Hash, err *object.Error) {
+	if !err.Pos.IsValid() {
+		return
+	}
+
+	kind := err.Kind
+	if kind == "" {
+		kind = object.ERROR_KIND
+	}
+
+	hash.Set(&object.String{Value: "line"}, &object.Integer{Value: int64(err.Pos.Line)})
+	hash.Set(&object.String{Value: "column"}, &object.Integer{Value: int64(err.Pos.Column)})
+	hash.Set(&object.String{Value: "stack"}, &object.String{Value: kind + ": " + err.Message + "\n" + err.StackTrace()})
+}
@@ -0,0 +1,175 @@
+package evaluator
+
+/*
+	相等性比较
+	=== 和 !== 是严格相等: 类型不同即不相等(Integer与Float同属数字)
+	== 和 != 是宽松相等: 按照类似JavaScript的规则先做类型转换再比较
+		null 与 undefined 彼此相等, 不等于其他任何值
+		布尔值先转换为数字(true为1, false为0)
+		数字与字符串比较时, 字符串转换为数字
+		大整数可以与数字以及能解析为整数的字符串比较
+	数组、哈希表和函数按引用比较
+*/
+
+import (
+	"finger/object"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+/*
+	严格相等
+*/
+func strictEquals(left, right object.Object) bool {
+	switch {
+	case isNumber(left) && isNumber(right):
+		return numbersEqual(left, right)
+	case left.Type() != right.Type():
+		return false
+	}
+
+	switch left := left.(type) {
+	case *object.BigInt:
+		return left.Value.Cmp(right.(*object.BigInt).Value) == 0
+	case *object.String:
+		return left.Value == right.(*object.String).Value
+	case *object.Boolean:
+		return left.Value == right.(*object.Boolean).Value
+	default:
+		return left == right
+	}
+}
+
+/*
+	宽松相等
+*/
+func looseEquals(left, right object.Object) bool {
+	switch {
+	case isNullish(left) || isNullish(right):
+		return isNullish(left) && isNullish(right)
+	case left.Type() == right.Type(), isNumber(left) && isNumber(right):
+		return strictEquals(left, right)
+	case left.Type() == object.BOOLEAN_OBJ:
+		return looseEquals(booleanToInteger(left), right)
+	case right.Type() == object.BOOLEAN_OBJ:
+		return looseEquals(left, booleanToInteger(right))
+	case isNumeric(left) && right.Type() == object.STRING_OBJ:
+		return looseEquals(left, stringToNumber(right.(*object.String).Value, left.Type() == object.BIGINT_OBJ))
+	case left.Type() == object.STRING_OBJ && isNumeric(right):
+		return looseEquals(stringToNumber(left.(*object.String).Value, right.Type() == object.BIGINT_OBJ), right)
+	case isNumeric(left) && isNumeric(right):
+		return numbersEqual(left, right)
+	default:
+		return false
+	}
+}
+
+/*
+	比较两个数字(Integer, Float或BigInt)的数值是否相等
+*/
+func numbersEqual(left, right object.Object) bool {
+	switch {
+	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+		return left.(*object.Integer).Value == right.(*object.Integer).Value
+	case left.Type() == object.BIGINT_OBJ && right.Type() == object.BIGINT_OBJ:
+		return left.(*object.BigInt).Value.Cmp(right.(*object.BigInt).Value) == 0
+	case left.Type() == object.BIGINT_OBJ:
+		return compareBigInt(left.(*object.BigInt).Value, right) == 0
+	case right.Type() == object.BIGINT_OBJ:
+		return compareBigInt(right.(*object.BigInt).Value, left) == 0
+	default:
+		return toFloat(left) == toFloat(right)
+	}
+}
+
+/*
+	比较两个数字(Integer, Float或BigInt), 返回-1, 0或1; 有NaN参与时返回2
+*/
+func compareNumbers(left, right object.Object) int {
+	switch {
+	case left.Type() == object.BIGINT_OBJ && right.Type() == object.BIGINT_OBJ:
+		return left.(*object.BigInt).Value.Cmp(right.(*object.BigInt).Value)
+	case left.Type() == object.BIGINT_OBJ:
+		return compareBigInt(left.(*object.BigInt).Value, right)
+	case right.Type() == object.BIGINT_OBJ:
+		if cmp := compareBigInt(right.(*object.BigInt).Value, left); cmp != 2 {
+			return -cmp
+		}
+		return 2
+	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+		leftVal, rightVal := left.(*object.Integer).Value, right.(*object.Integer).Value
+		switch {
+		case leftVal < rightVal:
+			return -1
+		case leftVal > rightVal:
+			return 1
+		}
+		return 0
+	}
+
+	leftVal, rightVal := toFloat(left), toFloat(right)
+	switch {
+	case math.IsNaN(leftVal) || math.IsNaN(rightVal):
+		return 2
+	case leftVal < rightVal:
+		return -1
+	case leftVal > rightVal:
+		return 1
+	}
+	return 0
+}
+
+/*
+	检查对象是否是任意一种数字(包括BigInt)
+*/
+func isNumeric(obj object.Object) bool {
+	return isNumber(obj) || obj.Type() == object.BIGINT_OBJ
+}
+
+func booleanToInteger(obj object.Object) object.Object {
+	if obj.(*object.Boolean).Value {
+		return &object.Integer{Value: 1}
+	}
+	return &object.Integer{Value: 0}
+}
+
+/*
+	将字符串转换为数字, 空白字符串为0, 无法解析时为NaN
+	asBigInt为真时按整数解析为BigInt, 无法解析时同样为NaN
+*/
+func stringToNumber(str string, asBigInt bool) object.Object {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return &object.Integer{Value: 0}
+	}
+
+	if asBigInt {
+		if value, ok := new(big.Int).SetString(str, 10); ok {
+			return &object.BigInt{Value: value}
+		}
+		return &object.Float{Value: math.NaN()}
+	}
+
+	if value, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return &object.Integer{Value: value}
+	}
+
+	switch str {
+	case "Infinity", "+Infinity":
+		return &object.Float{Value: math.Inf(1)}
+	case "-Infinity":
+		return &object.Float{Value: math.Inf(-1)}
+	}
+
+	// ParseFloat还接受 inf、nan、十六进制浮点数和下划线, 这些都不是合法的数字字符串
+	if strings.ContainsAny(str, "_xXpPiInN") {
+		return &object.Float{Value: math.NaN()}
+	}
+	if value, err := strconv.ParseFloat(str, 64); err == nil {
+		return &object.Float{Value: value}
+	}
+
+	return &object.Float{Value: math.NaN()}
+}
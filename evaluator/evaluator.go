@@ -7,7 +7,11 @@ package evaluator
 import (
 	"finger/ast"
 	"finger/object"
+	"finger/token"
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 )
 
 // 避免每次都创建新的object.Boolean, 使用全局变量引用提高性能
@@ -15,6 +19,9 @@ var (
 	TRUE = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
 	NULL = &object.Null{}
+	UNDEFINED = &object.Undefined{}
+	BREAK = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 /*
@@ -31,27 +38,50 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	// 整数
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	// 浮点数
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+	// 大整数
+	case *ast.BigIntLiteral:
+		return &object.BigInt{Value: node.Value}
 	// 布尔值
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
+	// null
+	case *ast.NullLiteral:
+		return NULL
+	// undefined
+	case *ast.UndefinedLiteral:
+		return UNDEFINED
 	// 前缀表达式
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
+			// typeof 未声明的变量得到"undefined"而不是错误
+			if _, ok := node.Right.(*ast.Identifier); ok && node.Operator == "typeof" {
+				return &object.String{Value: "undefined"}
+			}
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return withPosition(evalPrefixExpression(node.Operator, right), node.Token)
 	// 中缀表达式
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
+		// 空值合并是短路运算, 左边不是null或undefined时不求值右边
+		if node.Operator == "??" {
+			if isNullish(left) {
+				return Eval(node.Right, env)
+			}
+			return left
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return withPosition(evalInfixExpression(node.Operator, left, right, env), node.Token)
 	// 块语句
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
@@ -60,6 +90,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalIfExpression(node, env)
 	// 返回语句
 	case *ast.ReturnStatement:
+		// 不带返回值的return返回undefined
+		if node.ReturnValue == nil {
+			return &object.ReturnValue{Value: UNDEFINED}
+		}
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
@@ -74,6 +108,18 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(val) {
 			return val
 		}
+		// let Point = class {...} 和 let f = fn() {...} 中的匿名类和函数以变量名命名
+		switch val := val.(type) {
+		case *object.Class:
+			if val.Name == "" {
+				val.Name = node.Name.Value
+			}
+		case *object.Function:
+			if val.Name == "" {
+				val.Name = node.Name.Value
+			}
+		}
+		env.Set(node.Name.Value, val)
 		return val
 	// 标识符
 	case *ast.Identifier:
@@ -82,9 +128,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return &object.Function{Parameters: params, Body: body, Env: env, Doc: node.Doc, Generator: node.Generator, Async: node.Async}
 	// 函数调用
 	case *ast.CallExpression:
+		if _, ok := node.Function.(*ast.SuperExpression); ok {
+			return evalSuperCall(node, env)
+		}
 		function := Eval(node.Function, env)
 		if isError(function) {
 			return function
@@ -93,10 +142,16 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return withPosition(applyFunction(function, args, node.Token.Pos), node.Token)
 	// 字符串
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	// 模板字符串
+	case *ast.TemplateLiteral:
+		return evalTemplateLiteral(node, env)
+	// 带标签的模板字符串
+	case *ast.TaggedTemplateExpression:
+		return evalTaggedTemplate(node, env)
 	// 数组
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
@@ -114,10 +169,49 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return withPosition(evalIndexExpression(left, index), node.Token)
 	// 哈希表
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	// 循环与异常
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForOfStatement:
+		return evalForOfStatement(node, env)
+	case *ast.YieldExpression:
+		return evalYieldExpression(node, env)
+	case *ast.AwaitExpression:
+		return evalAwaitExpression(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
+	// 成员访问
+	case *ast.MemberExpression:
+		return evalMemberExpression(node, env)
+	// 赋值
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+	// 类
+	case *ast.ClassLiteral:
+		return evalClassLiteral(node, env)
+	case *ast.ClassStatement:
+		class := evalClassLiteral(node.Class, env)
+		if isError(class) {
+			return class
+		}
+		env.Set(node.Class.Name.Value, class)
+		return class
+	case *ast.NewExpression:
+		return evalNewExpression(node, env)
+	case *ast.ThisExpression:
+		return evalThisExpression(node, env)
+	case *ast.SuperExpression:
+		return withPosition(newError("'super' keyword unexpected here"), node.Token)
 	}
 	
 	return nil
@@ -132,7 +226,11 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	if global, ok := globals[node.Value]; ok {
+		return global
+	}
+
+	return withPosition(newReferenceError("identifier not found: " + node.Value), node.Token)
 }
 
 
@@ -155,8 +253,12 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalBitNotPrefixOperatorExpression(right)
+	case "typeof":
+		return &object.String{Value: typeOf(right)}
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newTypeError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -166,40 +268,163 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 		return FALSE
 	case FALSE:
 		return TRUE
-	case NULL:
+	case NULL, UNDEFINED:
 		return TRUE
 	default:
 		return FALSE
 	}
 }
 
+/*
+	typeof运算符的结果
+*/
+func typeOf(obj object.Object) string {
+	switch obj.(type) {
+	case *object.Integer, *object.Float:
+		return "number"
+	case *object.BigInt:
+		return "bigint"
+	case *object.String:
+		return "string"
+	case *object.Boolean:
+		return "boolean"
+	case *object.Function, *object.Builtin, *object.BoundMethod, *object.Class:
+		return "function"
+	case *object.Array:
+		return "array"
+	case *object.Hash, *object.Instance, *object.Generator, *object.Iterator, *object.Promise:
+		return "object"
+	case *object.Null:
+		return "null"
+	case *object.Undefined:
+		return "undefined"
+	default:
+		return strings.ToLower(string(obj.Type()))
+	}
+}
+
+/*
+	in运算符: 检查哈希表(包括原型链)、实例或类中是否有某个键, 或者数组中是否有某个索引
+*/
+func evalInExpression(key, container object.Object) object.Object {
+	switch container := container.(type) {
+	case *object.Hash:
+		hashKey, ok := object.AsHashable(key)
+		if !ok {
+			return newTypeError("unusable as hash key: %s", key.Type())
+		}
+		_, found := container.Lookup(hashKey)
+		return nativeBoolToBooleanObject(found)
+	case *object.Array:
+		index, ok := key.(*object.Integer)
+		return nativeBoolToBooleanObject(ok && index.Value >= 0 && index.Value < int64(len(container.Elements)))
+	case *object.Instance:
+		name, ok := key.(*object.String)
+		if !ok {
+			return FALSE
+		}
+		_, isField := container.Fields.Get(name)
+		method, _ := container.Class.FindMethod(name.Value)
+		return nativeBoolToBooleanObject(isField || method != nil)
+	case *object.Class:
+		name, ok := key.(*object.String)
+		if !ok {
+			return FALSE
+		}
+		static, _ := container.FindStatic(name.Value)
+		return nativeBoolToBooleanObject(static != nil)
+	default:
+		return newTypeError("cannot use 'in' operator to search for %s in %s", key.Inspect(), container.Type())
+	}
+}
+
+/*
+	检查对象是否是null或undefined
+*/
+func isNullish(obj object.Object) bool {
+	return obj == NULL || obj == UNDEFINED
+}
+
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	case *object.BigInt:
+		return &object.BigInt{Value: new(big.Int).Neg(right.Value)}
+	default:
+		return newTypeError("unknown operator: -%s", right.Type())
+	}
+}
+
+func evalBitNotPrefixOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: ^right.Value}
+	case *object.BigInt:
+		return &object.BigInt{Value: new(big.Int).Not(right.Value)}
+	default:
+		return newTypeError("unknown operator: ~%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+func evalInfixExpression(operator string, left, right object.Object, env *object.Environment) object.Object {
+	switch operator {
+	case "===":
+		return nativeBoolToBooleanObject(strictEquals(left, right))
+	case "!==":
+		return nativeBoolToBooleanObject(!strictEquals(left, right))
+	case "==":
+		return nativeBoolToBooleanObject(looseEquals(left, right))
+	case "!=":
+		return nativeBoolToBooleanObject(!looseEquals(left, right))
+	case "instanceof":
+		return evalInstanceof(left, right)
+	case "in":
+		return evalInExpression(left, right)
+	}
+
 	switch {
 		case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-			return evalIntegerInfixExpression(operator, left, right)
-		case operator == "==":
-			return nativeBoolToBooleanObject(left == right)
-		case operator == "!=":
-			return nativeBoolToBooleanObject(left != right)
+			return evalIntegerInfixExpression(operator, left, right, overflowMode(env))
+		case left.Type() == object.BIGINT_OBJ && right.Type() == object.BIGINT_OBJ:
+			return evalBigIntInfixExpression(operator, left, right)
+		// 大整数不会隐式转换, 与其他数字只能比较
+		case isNumber(left) && right.Type() == object.BIGINT_OBJ, left.Type() == object.BIGINT_OBJ && isNumber(right):
+			return evalMixedBigIntInfixExpression(operator, left, right, overflowMode(env))
+		// 整数与浮点数混合运算时, 整数提升为浮点数
+		case isNumber(left) && isNumber(right):
+			return evalFloatInfixExpression(operator, toFloat(left), toFloat(right))
 		case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 			return evalStringInfixExpression(operator, left, right)
+		// 只要有一边是字符串, + 就是字符串拼接
+		case operator == "+" && (left.Type() == object.STRING_OBJ || right.Type() == object.STRING_OBJ):
+			return &object.String{Value: toDisplayString(left) + toDisplayString(right)}
+		// 字符串重复 "ab" * 3 | 3 * "ab"
+		case operator == "*" && left.Type() == object.STRING_OBJ && right.Type() == object.INTEGER_OBJ:
+			return repeatString(left.(*object.String), right.(*object.Integer))
+		case operator == "*" && left.Type() == object.INTEGER_OBJ && right.Type() == object.STRING_OBJ:
+			return repeatString(right.(*object.String), left.(*object.Integer))
 		default:
-			return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+			return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+func evalIntegerInfixExpression(operator string, left, right object.Object, mode OverflowMode) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	// 按溢出处理方式检查 + - * ** <<
+	if mode != OverflowWrap && (operator != "**" && operator != "<<" || rightVal >= 0) {
+		if _, overflowed := checkedIntegerOperation(operator, leftVal, rightVal); overflowed {
+			if mode == OverflowPromote {
+				return promotedIntegerOperation(operator, leftVal, rightVal)
+			}
+			return newRangeError("integer overflow: %d %s %d", leftVal, operator, rightVal)
+		}
+	}
+
 	switch operator {
 	case "+":
 		return &object.Integer{Value: leftVal + rightVal}
@@ -208,17 +433,251 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newRangeError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newRangeError("division by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "**":
+		// 负指数的结果不是整数
+		if rightVal < 0 {
+			return &object.Float{Value: math.Pow(float64(leftVal), float64(rightVal))}
+		}
+		return &object.Integer{Value: integerPow(leftVal, rightVal)}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<":
+		if rightVal < 0 {
+			return newRangeError("negative shift count: %d", rightVal)
+		}
+		return &object.Integer{Value: leftVal << rightVal}
+	case ">>":
+		if rightVal < 0 {
+			return newRangeError("negative shift count: %d", rightVal)
+		}
+		return &object.Integer{Value: leftVal >> rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+/*
+	浮点数运算, 遵循IEEE 754: 除以0得到Infinity或NaN
+*/
+func evalFloatInfixExpression(operator string, leftVal, rightVal float64) object.Object {
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", object.FLOAT_OBJ, operator, object.FLOAT_OBJ)
+	}
+}
+
+/*
+	整数幂运算(快速幂), 溢出时与其他整数运算一样回绕
+*/
+func integerPow(base, exp int64) int64 {
+	result := int64(1)
+
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+
+	return result
+}
+
+/*
+	大整数运算
+*/
+func evalBigIntInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.BigInt).Value
+	rightVal := right.(*object.BigInt).Value
+	result := new(big.Int)
+
+	switch operator {
+	case "+":
+		result.Add(leftVal, rightVal)
+	case "-":
+		result.Sub(leftVal, rightVal)
+	case "*":
+		result.Mul(leftVal, rightVal)
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newRangeError("division by zero")
+		}
+		// 与整数一样向零取整
+		result.Quo(leftVal, rightVal)
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newRangeError("division by zero")
+		}
+		result.Rem(leftVal, rightVal)
+	case "**":
+		if rightVal.Sign() < 0 {
+			return newRangeError("bigint exponent must be non-negative")
+		}
+		result.Exp(leftVal, rightVal, nil)
+	case "&":
+		result.And(leftVal, rightVal)
+	case "|":
+		result.Or(leftVal, rightVal)
+	case "^":
+		result.Xor(leftVal, rightVal)
+	case "<<", ">>":
+		if rightVal.Sign() < 0 || !rightVal.IsUint64() || rightVal.Uint64() > math.MaxUint32 {
+			return newRangeError("invalid shift count: %s", rightVal.String())
+		}
+		if operator == "<<" {
+			result.Lsh(leftVal, uint(rightVal.Uint64()))
+		} else {
+			result.Rsh(leftVal, uint(rightVal.Uint64()))
+		}
+	default:
+		return evalNumberComparison(operator, leftVal.Cmp(rightVal), left, right)
+	}
+
+	return &object.BigInt{Value: result}
+}
+
+/*
+	大整数与Integer或Float之间的运算, 只支持比较
+	自动提升模式下Integer会先转换为BigInt, 以便溢出提升后的结果继续参与运算
+*/
+func evalMixedBigIntInfixExpression(operator string, left, right object.Object, mode OverflowMode) object.Object {
+	if mode == OverflowPromote && left.Type() != object.FLOAT_OBJ && right.Type() != object.FLOAT_OBJ {
+		return evalBigIntInfixExpression(operator, toBigInt(left), toBigInt(right))
+	}
+
+	return evalNumberComparison(operator, compareNumbers(left, right), left, right)
+}
+
+/*
+	比较大整数和Integer或Float, 返回-1, 0或1
+	与NaN比较时返回2, 使所有比较运算都为假
+*/
+func compareBigInt(value *big.Int, other object.Object) int {
+	switch other := other.(type) {
+	case *object.Integer:
+		return value.Cmp(big.NewInt(other.Value))
+	case *object.Float:
+		if math.IsNaN(other.Value) {
+			return 2
+		}
+		if math.IsInf(other.Value, 0) {
+			return -int(math.Copysign(1, other.Value))
+		}
+		return new(big.Float).SetInt(value).Cmp(big.NewFloat(other.Value))
+	default:
+		return 2
+	}
+}
+
+/*
+	根据比较结果计算比较运算符的值, cmp为2表示不可比较(NaN)
+*/
+func evalNumberComparison(operator string, cmp int, left, right object.Object) object.Object {
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(cmp == -1)
+	case ">":
+		return nativeBoolToBooleanObject(cmp == 1)
+	case "<=":
+		return nativeBoolToBooleanObject(cmp == -1 || cmp == 0)
+	case ">=":
+		return nativeBoolToBooleanObject(cmp == 1 || cmp == 0)
+	case "==":
+		return nativeBoolToBooleanObject(cmp == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(cmp != 0)
+	}
+
+	if left.Type() != right.Type() {
+		return newTypeError("cannot mix %s and %s in %s, use explicit conversion", left.Type(), right.Type(), operator)
+	}
+
+	return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+/*
+	将Integer或BigInt转换为BigInt
+*/
+func toBigInt(obj object.Object) *object.BigInt {
+	if i, ok := obj.(*object.Integer); ok {
+		return &object.BigInt{Value: big.NewInt(i.Value)}
+	}
+
+	return obj.(*object.BigInt)
+}
+
+/*
+	检查对象是否是数字(Integer或Float)
+*/
+func isNumber(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.Float:
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+	将数字对象转换为float64
+*/
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.Float:
+		return obj.Value
+	default:
+		return math.NaN()
 	}
 }
 
@@ -241,7 +700,7 @@ func isTruthy(obj object.Object) bool {
 		return true
 	case FALSE:
 		return false
-	case NULL:
+	case NULL, UNDEFINED:
 		return false
 	default:
 		return true
@@ -251,6 +710,8 @@ func isTruthy(obj object.Object) bool {
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
+	applyDirectives(program, env)
+
 	for _, stmt := range program.Statements {
 		result = Eval(stmt, env)
 
@@ -273,7 +734,7 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -282,8 +743,90 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+/*
+	创建运行时错误, 同时记录当前的调用栈
+*/
 func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+	return &object.Error{Message: fmt.Sprintf(format, a...), Stack: captureStack()}
+}
+
+func newTypeError(format string, a ...interface{}) *object.Error {
+	err := newError(format, a...)
+	err.Kind = object.TYPE_ERROR
+	return err
+}
+
+func newReferenceError(format string, a ...interface{}) *object.Error {
+	err := newError(format, a...)
+	err.Kind = object.REFERENCE_ERROR
+	return err
+}
+
+func newRangeError(format string, a ...interface{}) *object.Error {
+	err := newError(format, a...)
+	err.Kind = object.RANGE_ERROR
+	return err
+}
+
+/*
+	模板字符串求值, 插值的值通过Inspect转换为字符串
+*/
+func evalTemplateLiteral(node *ast.TemplateLiteral, env *object.Environment) object.Object {
+	values := evalExpressions(node.Expressions, env)
+	if len(values) == 1 && isError(values[0]) {
+		return values[0]
+	}
+
+	var out strings.Builder
+	for i, quasi := range node.Quasis {
+		out.WriteString(quasi)
+		if i < len(values) {
+			out.WriteString(values[i].Inspect())
+		}
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+/*
+	带标签的模板字符串求值
+	标签函数的第一个参数是冻结的文本片段数组, 其余参数依次是各个插值的值
+	文本片段已经处理了转义, 未处理转义的原始文本通过第一个参数的raw属性读取
+*/
+func evalTaggedTemplate(node *ast.TaggedTemplateExpression, env *object.Environment) object.Object {
+	tag := Eval(node.Tag, env)
+	if isError(tag) {
+		return tag
+	}
+
+	values := evalExpressions(node.Template.Expressions, env)
+	if len(values) == 1 && isError(values[0]) {
+		return values[0]
+	}
+
+	quasis := make([]object.Object, len(node.Template.Quasis))
+	for i, quasi := range node.Template.Quasis {
+		quasis[i] = &object.String{Value: quasi}
+	}
+	raws := make([]object.Object, len(node.Template.Raws))
+	for i, raw := range node.Template.Raws {
+		raws[i] = &object.String{Value: raw}
+	}
+	strs := &object.Array{Elements: quasis, Frozen: true, Raw: &object.Array{Elements: raws, Frozen: true}}
+
+	args := append([]object.Object{strs}, values...)
+	return withPosition(applyFunction(tag, args, node.Token.Pos), node.Token)
+}
+
+/*
+	为尚未记录位置的错误补充源码位置
+*/
+func withPosition(obj object.Object, tok token.Token) object.Object {
+	if err, ok := obj.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = tok.Pos
+	}
+
+	return obj
 }
 
 func isError(obj object.Object) bool {
@@ -307,23 +850,66 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+/*
+	调用函数, callSite是调用位置, 记录在调用栈中
+*/
+func applyFunction(fn object.Object, args []object.Object, callSite token.Position) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		return callFunction(fn, extendFunctionEnv(fn, args), functionName(fn, nil), callSite)
+	case *object.BoundMethod:
+		extendedEnv := extendFunctionEnv(fn.Fn, args)
+		extendedEnv.Set("this", fn.Receiver)
+		if fn.Home != nil {
+			extendedEnv.Set("super", fn.Home)
+		}
+		return callFunction(fn.Fn, extendedEnv, functionName(fn.Fn, fn.Home), callSite)
 	case *object.Builtin:
-		return fn.Fn(args...)
+		result := fn.Fn(args...)
+		// 内置函数自身报告的错误没有更具体的种类
+		if err, ok := result.(*object.Error); ok && err.Kind == "" {
+			err.Kind = object.BUILTIN_ERROR
+		}
+		return result
+	case *object.Class:
+		return newTypeError("class constructor %s cannot be invoked without 'new'", fn.Name)
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newTypeError("not a function: %s", fn.Type())
+	}
+}
+
+/*
+	在准备好的环境中执行函数体, 执行期间调用栈中有这个函数的一帧
+*/
+func callFunction(fn *object.Function, env *object.Environment, name string, callSite token.Position) object.Object {
+	// 调用生成器函数只创建生成器, 函数体由next驱动执行
+	if fn.Generator {
+		return newGenerator(fn, env, name, callSite)
 	}
+	if fn.Async {
+		return callAsyncFunction(fn, env, name, callSite)
+	}
+
+	pushFrame(name, callSite)
+	defer popFrame()
+
+	evaluated := Eval(fn.Body, env)
+	// 没有返回值的函数返回undefined
+	if evaluated == nil {
+		return UNDEFINED
+	}
+	return unwrapReturnValue(evaluated)
 }
 
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for paramIdx, param := range fn.Parameters {
+		// 缺失的参数为undefined
+		if paramIdx >= len(args) {
+			env.Set(param.Value, UNDEFINED)
+			continue
+		}
 		env.Set(param.Value, args[paramIdx])
 	}
 
@@ -340,24 +926,76 @@ func unwrapReturnValue(obj object.Object) object.Object {
 }
 
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
-
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
 
-	return &object.String{Value: leftVal + rightVal}
+	// 比较运算按字典序(即Unicode码点顺序)进行
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	default:
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// 字符串的最大长度(字节), 更长的重复结果会耗尽内存
+const maxStringLength = 1 << 30
+
+/*
+	字符串重复, 次数不能为负数, 结果不能超过maxStringLength
+*/
+func repeatString(str *object.String, count *object.Integer) object.Object {
+	if count.Value < 0 {
+		return newRangeError("invalid string repeat count: %d", count.Value)
+	}
+	if len(str.Value) > 0 && count.Value > maxStringLength/int64(len(str.Value)) {
+		return newRangeError("string repeat count too large: %d", count.Value)
+	}
+
+	return &object.String{Value: strings.Repeat(str.Value, int(count.Value))}
+}
+
+/*
+	将对象转换为字符串拼接时使用的文本
+	字符串不带引号, 大整数不带后缀n
+*/
+func toDisplayString(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.String:
+		return obj.Value
+	case *object.BigInt:
+		return obj.Value.String()
+	default:
+		return obj.Inspect()
+	}
 }
 
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
+	case (left.Type() == object.ARRAY_OBJ || left.Type() == object.STRING_OBJ) && isIteratorKey(index):
+		return iteratorHook(left)
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.STRING_OBJ && left.(*object.Array).Raw != nil:
+		return getProperty(left, index.(*object.String).Value)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
+	case (left.Type() == object.INSTANCE_OBJ || left.Type() == object.CLASS_OBJ ||
+		left.Type() == object.GENERATOR_OBJ || left.Type() == object.ITERATOR_OBJ ||
+		left.Type() == object.PROMISE_OBJ) && index.Type() == object.STRING_OBJ:
+		return getProperty(left, index.(*object.String).Value)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newTypeError("index operator not supported: %s", left.Type())
 	}
 }
 
@@ -368,57 +1006,131 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	max := int64(len(arrayObject.Elements) - 1)
 
 	if idx < 0 || idx > max {
-		return NULL
+		return UNDEFINED
 	}
 
 	return arrayObject.Elements[idx]
 }
 
+/*
+	字符串索引按字符(Unicode码点)计算, 返回只包含该字符的字符串
+*/
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	value := str.(*object.String).Value
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 {
+		return UNDEFINED
+	}
+
+	for _, ch := range value {
+		if idx == 0 {
+			return &object.String{Value: string(ch)}
+		}
+		idx--
+	}
+
+	return UNDEFINED
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
+
+	for _, prop := range node.Properties {
+		// 展开属性: 按顺序复制另一个哈希表的键值对
+		if prop.Kind == ast.PropertySpread {
+			source := Eval(prop.Value, env)
+			if isError(source) {
+				return source
+			}
+			if isNullish(source) {
+				continue
+			}
+			other, ok := source.(*object.Hash)
+			if !ok {
+				return newTypeError("cannot spread %s into hash", source.Type())
+			}
+			for _, pair := range other.Pairs() {
+				hash.Set(pair.Key.(object.Hashable), pair.Value)
+			}
+			continue
+		}
+
+		// {__proto__: value} 设置原型而不是添加键
+		if ident, ok := prop.Key.(*ast.Identifier); ok && ident.Value == "__proto__" && prop.Kind == ast.PropertyKeyValue && !prop.Computed {
+			proto := Eval(prop.Value, env)
+			if isError(proto) {
+				return proto
+			}
+			if err := setPrototype(hash, proto); err != nil {
+				return withPosition(err, ident.Token)
+			}
+			continue
+		}
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+		key := evalPropertyKey(prop, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newTypeError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(prop.Value, env)
 
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		// 方法简写以属性名命名
+		if fn, ok := value.(*object.Function); ok && prop.Kind == ast.PropertyMethod {
+			fn.Name = toDisplayString(key)
+		}
+
+		hash.Set(hashKey, value)
 	}
 	
-	return &object.Hash{Pairs: pairs}
+	return hash
+}
+
+/*
+	求值属性名: 非计算属性中的标识符按字符串处理, 其余按表达式求值
+*/
+func evalPropertyKey(prop *ast.HashProperty, env *object.Environment) object.Object {
+	if ident, ok := prop.Key.(*ast.Identifier); ok && !prop.Computed {
+		return &object.String{Value: ident.Value}
+	}
+
+	return Eval(prop.Key, env)
 }
 
 
+/*
+	哈希表索引求值, 自身没有的键沿原型链查找
+*/
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	if str, ok := index.(*object.String); ok && str.Value == "__proto__" {
+		return protoOf(hashObject)
+	}
+
+	key, ok := object.AsHashable(index)
 
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newTypeError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	value, ok := hashObject.Lookup(key)
 
 	if !ok {
-		return NULL
+		return UNDEFINED
 	}
 
-	return pair.Value
+	return value
 }
 
 
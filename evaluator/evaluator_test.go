@@ -1 +1,1298 @@
 package evaluator
+
+import (
+	"finger/lexer"
+	"finger/object"
+	"finger/parser"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestEnhancedHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let h = {one: 1}; h["one"]`, 1},
+		{`let name = "finger"; let h = {name}; h["name"]`, "finger"},
+		{`let prefix = "user_"; let h = {[prefix + "id"]: 7}; h["user_id"]`, 7},
+		{`let h = {double(x) { x * 2 }}; h["double"](21)`, 42},
+		{`let defaults = {x: 0, y: 0}; let h = {...defaults, x: 1}; h["x"]`, 1},
+		{`let defaults = {x: 0, y: 0}; let h = {...defaults, x: 1}; h["y"]`, 0},
+		{`let h = {x: 1, ...{x: 2}}; h["x"]`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+}
+
+func TestHashSpreadError(t *testing.T) {
+	evaluated := testEval(`{...5}`)
+	testErrorObject(t, evaluated, "cannot spread INTEGER into hash")
+}
+
+func TestHashInspectIsDeterministic(t *testing.T) {
+	input := `{"z": 1, "a": 2, 10: 3, true: 4, "m": {"y": 5, "b": 6}}`
+	expected := `{z: 1, a: 2, 10: 3, true: 4, m: {y: 5, b: 6}}`
+
+	for i := 0; i < 20; i++ {
+		evaluated := testEval(input)
+		if evaluated.Inspect() != expected {
+			t.Fatalf("Inspect() wrong. expected=%q, got=%q", expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestNumericTower(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"0x10 + 0o10 + 0b10 + 1_000", 1026},
+		{"7 % 3", 1},
+		{"7 / 2", 3},
+		{"3.5", 3.5},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2.0},
+		{"10 / 4.0", 2.5},
+		{"-2.5", -2.5},
+		{"7.5 % 2", 1.5},
+		{"1 < 1.5", true},
+		{"2 >= 2.0", true},
+		{"2 == 2.0", true},
+		{"3 <= 2", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3.0", "3.0"},
+		{"0.1 + 0.2", "0.30000000000000004"},
+		{"1e-9", "1e-9"},
+		{"1e21", "1e+21"},
+		{"1.0 / 0", "Infinity"},
+		{"-1.0 / 0", "-Infinity"},
+		{"0.0 / 0", "NaN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: Inspect() wrong. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestIntegerDivisionByZero(t *testing.T) {
+	testErrorObject(t, testEval("1 / 0"), "division by zero")
+	testErrorObject(t, testEval("1 % 0"), "division by zero")
+}
+
+func TestBigIntArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807n + 1n", "9223372036854775808n"},
+		{"2n ** 100n", "1267650600228229401496703205376n"},
+		{"-7n / 2n", "-3n"},
+		{"-7n % 2n", "-1n"},
+		{"0xFFn & 0x0Fn", "15n"},
+		{"1n << 70n", "1180591620717411303424n"},
+		{"~0n", "-1n"},
+		{"1n < 2", "true"},
+		{"2.5 > 2n", "true"},
+		{"10n == 10", "true"},
+		{"BigInt(42) * 3n", "126n"},
+		{`BigInt("123456789012345678901234567890")`, "123456789012345678901234567890n"},
+		{"Number(42n) + 1", "43"},
+		{"Number(2n ** 64n)", "18446744073709552000.0"},
+		{"let h = {[2n ** 64n]: 1}; h[18446744073709551616n]", "1"},
+		{"2 ** 10", "1024"},
+		{"2 ** 3 ** 2", "512"},
+		{"2 ** -1", "0.5"},
+		{"6 & 3 | 8", "10"},
+		{"1 << 2 + 1", "8"},
+		{"~5", "-6"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: Inspect() wrong. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestBigIntErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1n + 1", "cannot mix BIGINT and INTEGER in +, use explicit conversion"},
+		{"1n / 0n", "division by zero"},
+		{"BigInt(1.5)", "cannot convert 1.5 to BIGINT: not an integer"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestCheckedIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// 默认回绕
+		{"9223372036854775807 + 1", "-9223372036854775808"},
+		{`"use checked"; 9223372036854775807 + 1`, "ERROR: integer overflow: 9223372036854775807 + 1 at 1:36"},
+		{`"use checked"; -9223372036854775807 - 2`, "ERROR: integer overflow: -9223372036854775807 - 2 at 1:37"},
+		{`"use checked"; 4294967296 * 4294967296`, "ERROR: integer overflow: 4294967296 * 4294967296 at 1:27"},
+		{`"use checked"; 3 ** 40`, "ERROR: integer overflow: 3 ** 40 at 1:18"},
+		{`"use checked"; 1 << 63`, "ERROR: integer overflow: 1 << 63 at 1:18"},
+		{`"use checked"; -1 << 63`, "-9223372036854775808"},
+		{`"use checked"; -2 << 63`, "ERROR: integer overflow: -2 << 63 at 1:19"},
+		{`"use checked"; -1 << 64`, "ERROR: integer overflow: -1 << 64 at 1:19"},
+		{`"use checked"; 3 ** 39 + (1 << 62) - 1`, "8664241171446364170"},
+		{`"use checked"; (-2) ** 63`, "-9223372036854775808"},
+		{`"use bigint"; 9223372036854775807 + 1`, "9223372036854775808n"},
+		{`"use bigint"; 2 ** 64 * 2`, "36893488147419103232n"},
+		{`"use bigint"; let f = fn(x) { x * x }; f(4294967296)`, "18446744073709551616n"},
+		{`"use checked"; let g = fn() { if (true) { fn(x) { x + 1 } } }; g()(9223372036854775807)`, "ERROR: integer overflow: 9223372036854775807 + 1 at 1:53"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: Inspect() wrong. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDefaultOverflowMode(t *testing.T) {
+	defer func(mode OverflowMode) { DefaultOverflowMode = mode }(DefaultOverflowMode)
+	DefaultOverflowMode = OverflowError
+
+	testErrorObject(t, testEval("9223372036854775807 + 1"), "integer overflow: 9223372036854775807 + 1")
+
+	// 源文件中的编译指示优先于宿主设置
+	evaluated := testEval(`"use wrapping"; 9223372036854775807 + 1`)
+	testIntegerObject(t, "use wrapping", evaluated, -9223372036854775808)
+}
+
+func TestNullAndUndefined(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"null", "null"},
+		{"undefined", "undefined"},
+		{`{"a": 1}["b"]`, "undefined"},
+		{"[1, 2][5]", "undefined"},
+		{"[1, 2][-1]", "undefined"},
+		{"let f = fn(a, b) { b }; f(1)", "undefined"},
+		{"let f = fn() { }; f()", "undefined"},
+		{"null == undefined", "true"},
+		{"null != undefined", "false"},
+		{"null == null", "true"},
+		{"null == 0", "false"},
+		{"undefined == false", "false"},
+		{"undefined != 1", "true"},
+		{"!null", "true"},
+		{"!undefined", "true"},
+		{"typeof null", "null"},
+		{"typeof undefined", "undefined"},
+		{"typeof 1", "number"},
+		{"typeof 1.5", "number"},
+		{"typeof 1n", "bigint"},
+		{`typeof "s"`, "string"},
+		{"typeof true", "boolean"},
+		{"typeof fn() {}", "function"},
+		{"typeof first", "function"},
+		{"typeof [1]", "array"},
+		{"typeof {}", "object"},
+		{"typeof missing", "undefined"},
+		{"class A {}; typeof A", "function"},
+		{"class A {}; typeof new A()", "object"},
+		{"class A { m() {} }; typeof new A().m", "function"},
+		{"null ?? 1", "1"},
+		{"undefined ?? 2", "2"},
+		{"0 ?? 3", "0"},
+		{"false ?? 3", "false"},
+		{`{"a": null}["a"] ?? "default"`, "default"},
+		{"null ?? undefined ?? 4", "4"},
+		{"1 ?? missing", "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: Inspect() wrong. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		// 严格相等
+		{`"a" === "a"`, true},
+		{`"a" === "b"`, false},
+		{"1 === 1.0", true},
+		{`1 === "1"`, false},
+		{"1n === 1", false},
+		{"1n === 1n", true},
+		{"null === undefined", false},
+		{"null === null", true},
+		{"true === 1", false},
+		{`1 !== "1"`, true},
+		{"0.0 / 0 === 0.0 / 0", false},
+		{"let a = [1]; a === a", true},
+		{"[1] === [1]", false},
+		// 宽松相等
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`1 == "1"`, true},
+		{`1.5 == "1.5"`, true},
+		{`0 == ""`, true},
+		{`1 == "x"`, false},
+		{`true == 1`, true},
+		{`true == "1"`, true},
+		{`false == 0`, true},
+		{`false == ""`, true},
+		{`true == 2`, false},
+		{`1n == 1`, true},
+		{`10n == "10"`, true},
+		{`null == 0`, false},
+		{`undefined == ""`, false},
+		{`null == undefined`, true},
+		{`"1" != 1`, false},
+		{"[1] == [1]", false},
+		{`typeof {}["x"] == "undefined"`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, tt.input, evaluated, tt.expected)
+	}
+}
+
+func TestDeepEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"equals([1, 2], [1, 2])", true},
+		{"equals([1, 2], [2, 1])", false},
+		{"equals([1, [2, 3]], [1, [2, 3]])", true},
+		{"equals([1], [1.0])", false},
+		{"equals(1, 1.0)", false},
+		{"equals(1.0, 1)", false},
+		{"equals([1.5], [1.5])", true},
+		{`equals({"a": 1}, {"a": 1.0})`, false},
+		{"equals(1n, 1.0)", false},
+		{`equals([1], ["1"])`, false},
+		{"equals([1n], [1])", false},
+		{`equals({"a": 1, "b": [2]}, {"b": [2], "a": 1})`, true},
+		{`equals({"a": 1}, {"a": 1, "b": 2})`, false},
+		{`equals({"a": null}, {"a": undefined})`, false},
+		{"equals(null, null)", true},
+		{"let f = fn() {}; equals(f, f)", true},
+		{"equals(fn() {}, fn() {})", false},
+		{"[1, 2] == [1, 2]", false},
+		{"isFrozen([1])", false},
+		{"isFrozen(freeze([1]))", true},
+		{"let a = freeze([[1]]); isFrozen(a[0])", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, tt.input, evaluated, tt.expected)
+	}
+}
+
+func TestFrozenValuesAsHashKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let k = freeze([1, 2]); let h = {[k]: 3}; h[freeze([1, 2])]", 3},
+		{`let k = freeze({"x": 1, "y": 2}); let h = {[k]: "point"}; h[freeze({"y": 2, "x": 1})]`, "point"},
+		{"let h = {[freeze([1, [2]])]: 4}; h[freeze([1, [2]])]", 4},
+		{`let h = {[freeze([1])]: 1, [freeze(["1"])]: 2}; h[freeze(["1"])]`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	testErrorObject(t, testEval("{[[1, 2]]: 3}"), "unusable as hash key: ARRAY")
+	testErrorObject(t, testEval("{[freeze([fn() {}])]: 3}"), "unusable as hash key: ARRAY")
+}
+
+func TestStringOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"apple" < "banana"`, true},
+		{`"apple" > "Apple"`, true},
+		{`"abc" <= "abc"`, true},
+		{`"abd" >= "abc"`, true},
+		{`"ab" < "abc"`, true},
+		{`"n=" + 5`, "n=5"},
+		{`5 + "px"`, "5px"},
+		{`"x" + 1.5`, "x1.5"},
+		{`"big " + 10n`, "big 10"},
+		{`"is " + true`, "is true"},
+		{`"v=" + null`, "v=null"},
+		{`"ab" * 3`, "ababab"},
+		{`2 * "-"`, "--"},
+		{`"ab" * 0`, ""},
+		{`compare("a", "b")`, -1},
+		{`compare("b", "a")`, 1},
+		{`compare("a", "a")`, 0},
+		{`compare("B", "a")`, -1},
+		{`compare("B", "a", "en")`, 1},
+		{`compare("a", "A", "en")`, -1},
+		{`compare("ä", "b", "de")`, -1},
+		{`compare("ä", "b", "sv")`, 1},
+		{`compare("ä", "b")`, 1},
+		{`compare("résumé", "resume", "fr")`, 1},
+		{`compare("a", "A")`, 1},
+		{`compare(2, 1.5)`, 1},
+		{`compare(1n, 2)`, -1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	testErrorObject(t, testEval(`"ab" * -1`), "invalid string repeat count: -1")
+	testErrorObject(t, testEval(`"ab" * 4611686018427387903`), "string repeat count too large: 4611686018427387903")
+	testErrorObject(t, testEval(`"x" * 1000000000000`), "string repeat count too large: 1000000000000")
+	testErrorObject(t, testEval(`try { "x" * 1000000000000 } catch (e) { throw e.kind }`), "uncaught RangeError")
+	testErrorObject(t, testEval(`"ab" - "a"`), "unknown operator: STRING - STRING")
+	testErrorObject(t, testEval(`compare("a", 1)`), "cannot compare STRING and INTEGER")
+	testErrorObject(t, testEval(`compare("a", "b", "xx-nonsense")`), `unsupported locale: "xx-nonsense"`)
+	testErrorObject(t, testEval(`compare("a", "b", "")`), `unsupported locale: ""`)
+}
+
+func TestUnicodeStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("你好")`, 2},
+		{`byteLength("你好")`, 6},
+		{`len("héllo")`, 5},
+		{`byteLength("héllo")`, 6},
+		{`"你好"[1]`, "好"},
+		{`"a😀b"[1]`, "😀"},
+		{`substr("你好世界", 1, 3)`, "好世"},
+		{`substr("你好世界", 2)`, "世界"},
+		{`substr("你好世界", -1)`, "界"},
+		{`substr("你好世界", 3, 1)`, ""},
+		{`let 问候 = "你好"; 问候 + "!"`, "你好!"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	if evaluated := testEval(`"你好"[2]`); evaluated != UNDEFINED {
+		t.Errorf("out of range string index should be undefined. got=%s", evaluated.Inspect())
+	}
+}
+
+func TestTemplateLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"`plain`", "plain"},
+		{"let name = \"finger\"; `hi, ${name}!`", "hi, finger!"},
+		{"`${1 + 2} ${1.5} ${true} ${[1, 2]} ${null}`", "3 1.5 true [1, 2] null"},
+		{"`a${ {k: 1}[\"k\"] }b`", "a1b"},
+		{"`${ `in${1}` }!`", "in1!"},
+		{"`line1\nline2`", "line1\nline2"},
+		{"let tag = fn(s, a, b) { s[0] + a + s[1] + b + s[2] }; tag`x${1}y${2}z`", "x1y2z"},
+		{"let count = fn(s) { first(s) }; count`only`", "only"},
+		{"let frozen = fn(s) { isFrozen(s) }; frozen`x`", true},
+		{"`a\\`b`", "a`b"},
+		{"let x = 1; `\\${x} is ${x}`", "${x} is 1"},
+		{"`tab\\there`", "tab\there"},
+		{"let cooked = fn(s) { s[0] }; cooked`a\\nb`", "a\nb"},
+		{"let raw = fn(s) { s.raw[0] + s.raw[1] }; raw`a\\n${1}\\``", "a\\n\\`"},
+		{"let raw = fn(s) { isFrozen(s.raw) }; raw`x`", true},
+		{`r"C:\dir\${x}"`, "C:\\dir\\${x}"},
+		{"r'multi\nline'", "multi\nline"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	testErrorObject(t, testEval("`a${-true}b`"), "unknown operator: -BOOLEAN")
+}
+
+func TestDocBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"/** Adds two numbers. */ let sum = fn(a, b) { a + b }; doc(sum)", "Adds two numbers."},
+		{"let obj = { /** Says hi. */ greet() { 1 } }; doc(obj[\"greet\"])", "Says hi."},
+		{"/* not a doc comment */ let f = fn() { 1 }; doc(f) == undefined", true},
+		{"doc(1) == undefined", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+}
+
+func TestContextualKeywords(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let map = {get: 1, delete: 2}; map["get"] + map["delete"]`, 3},
+		{`let filter = fn(xs) { first(xs) }; filter([7, 8])`, 7},
+		{`let all = [1, 2, 3]; len(all)`, 3},
+		{`let string = "s"; string`, "s"},
+		{`{if: 1, default() { 2 }}["if"]`, 1},
+		{`typeof print`, "function"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+}
+
+func TestAutomaticSemicolonInsertion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = 1\nlet b = 2\na + b", 3},
+		{"let f = fn() { return 5 }\nf()", 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	// return之后换行时不带返回值
+	if evaluated := testEval("let f = fn() { return\n5 }\nf()"); evaluated != UNDEFINED {
+		t.Errorf("return followed by a newline should return undefined. got=%s", evaluated.Inspect())
+	}
+}
+
+// 辅助函数
+func testEval(input string) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+
+	return Eval(program, env)
+}
+
+func TestClasses(t *testing.T) {
+	base := `
+class Animal {
+	legs = 4
+	static count = 0
+	constructor(name) { this.name = name; Animal.count = Animal.count + 1 }
+	speak() { this.name + " makes a sound" }
+	static kind() { "animal" }
+}
+class Dog extends Animal {
+	tricks = []
+	speak() { super.speak() + " loudly" }
+	static kind() { "dog, an " + super.kind() }
+}
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`new Animal("Cat").speak()`, "Cat makes a sound"},
+		{`new Dog("Rex").speak()`, "Rex makes a sound loudly"},
+		{`new Dog("Rex").legs`, 4},
+		{`new Dog("Rex")["name"]`, "Rex"},
+		{`new Dog("a"); new Animal("b"); Dog.count`, 2},
+		{`Dog.kind()`, "dog, an animal"},
+		{`let d = new Dog("Rex"); d.legs = 3; d.legs`, 3},
+		{`let d = new Dog("Rex"); let s = d.speak; s()`, "Rex makes a sound loudly"},
+		{`typeof new Dog("Rex")`, "object"},
+		{`typeof Dog`, "function"},
+		{`new Dog("Rex").missing == undefined`, true},
+		{`new Dog("Rex").tricks == new Dog("Rex").tricks`, false},
+		{`class C extends Dog { constructor() { super("Max"); this.legs = 3 } }; new C().speak() + new C().legs`, "Max makes a sound loudly3"},
+		{`let P = class { x = 1 }; new P().x`, 1},
+		{`class Counter { n = 0; inc() { this.n = this.n + 1; this } }; new Counter().inc().inc().n`, 2},
+		{`let h = {n: 2, get() { this.n }}; h.n = 5; h.get()`, 5},
+		{`let a = [1]; a[1] = 2; a[0] = 9; a[0] + a[1]`, 11},
+		{`let x = 1; let f = fn() { x = x + 1 }; f(); f(); x`, 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(base + tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	inspect := testEval(base + `new Dog("Rex")`).Inspect()
+	if inspect != "Dog {legs: 4, tricks: [], name: Rex}" {
+		t.Errorf("wrong instance inspect. got=%q", inspect)
+	}
+}
+
+func TestClassErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`class A {}; A()`, "class constructor A cannot be invoked without 'new'"},
+		{`new 1`, "not a class: INTEGER"},
+		{`class A extends 1 {}`, "class extends value is not a class: INTEGER"},
+		{`this`, "'this' is not defined outside of a method"},
+		{`class A { f() { super.f() } }; new A().f()`, "'super' used in class A which has no parent class"},
+		{`null.x`, `cannot read property "x" of NULL`},
+		{`y = 1`, "identifier not found: y"},
+		{`freeze({a: 1}).a = 2`, `cannot assign to property "a" of a frozen hash`},
+		{`let a = [1]; a[5] = 2`, "index out of range: 5"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestPrototypes(t *testing.T) {
+	base := `
+let animal = {legs: 4, speak() { this.name + " speaks" }}
+let dog = {__proto__: animal, name: "Rex"}
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`dog.legs`, 4},
+		{`dog["legs"]`, 4},
+		{`dog.speak()`, "Rex speaks"},
+		{`dog.__proto__ == animal`, true},
+		{`animal.__proto__`, nil},
+		{`dog.legs = 3; dog.legs + animal.legs`, 7},
+		{`let puppy = create(dog, {name: "Bit"}); puppy.speak() + puppy.legs`, "Bit speaks4"},
+		{`create(dog) instanceof animal`, true},
+		{`animal instanceof dog`, false},
+		{`dog instanceof dog`, false},
+		{`create(null).__proto__`, nil},
+		{`let cat = {name: "Tom"}; cat.__proto__ = animal; cat.speak()`, "Tom speaks"},
+		{`let cat = {__proto__: animal}; cat["__proto__"] = null; cat.legs`, nil},
+		{`class A {}; class B extends A {}; new B() instanceof A`, true},
+		{`class A {}; class B extends A {}; new A() instanceof B`, false},
+		{`class A {}; 1 instanceof A`, false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(base + tt.input)
+		if tt.expected == nil {
+			if evaluated != NULL && evaluated != UNDEFINED {
+				t.Errorf("%s: expected null or undefined. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+			continue
+		}
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`animal.__proto__ = dog`, "cyclic __proto__ value"},
+		{`{__proto__: 1}`, "object prototype may only be a HASH or null, got INTEGER"},
+		{`create(1)`, "object prototype may only be a HASH or null, got INTEGER"},
+		{`dog instanceof 1`, "right-hand side of 'instanceof' is not a class or hash: INTEGER"},
+	}
+
+	for _, tt := range errors {
+		testErrorObject(t, testEval(base+tt.input), tt.expected)
+	}
+}
+
+func TestInOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" in {"a": 1}`, true},
+		{`"b" in {"a": 1}`, false},
+		{`"a" in {"a": null}`, true},
+		{`1 in {1: "x"}`, true},
+		{`"legs" in create({legs: 4})`, true},
+		{`0 in [1, 2]`, true},
+		{`2 in [1, 2]`, false},
+		{`-1 in [1, 2]`, false},
+		{`"0" in [1, 2]`, false},
+		{`class A { x = undefined; m() {} }; "x" in new A()`, true},
+		{`class A { x = undefined; m() {} }; "m" in new A()`, true},
+		{`class A { x = undefined; m() {} }; "y" in new A()`, false},
+		{`class A { static s() {} }; class B extends A {}; "s" in B`, true},
+		{`!("a" in {})`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	testErrorObject(t, testEval(`"a" in "abc"`), "cannot use 'in' operator to search for a in STRING")
+	testErrorObject(t, testEval(`[1] in {}`), "unusable as hash key: ARRAY")
+}
+
+func TestWhileLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let i = 0; while (i < 5) { i = i + 1 }; i`, 5},
+		{`let i = 0; while (true) { i = i + 1; if (i == 3) { break } }; i`, 3},
+		{`let i = 0; let s = 0; while (i < 5) { i = i + 1; if (i % 2 == 0) { continue }; s = s + i }; s`, 9},
+		{`let f = fn() { let i = 0; while (true) { i = i + 1; if (i > 2) { return i } } }; f()`, 3},
+		{`let i = 0; let n = 0; while (i < 3) { i = i + 1; let j = 0; while (true) { j = j + 1; n = n + 1; if (j == 2) { break } } }; n`, 6},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+}
+
+func TestForOfLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let s = 0; for (let x of [1, 2, 3]) { s = s + x }; s`, 6},
+		{`let s = ""; for (let c of "héllo") { s = c + s }; s`, "olléh"},
+		{`let s = 0; for (let x of [1, 2, 3, 4]) { if (x == 3) { break }; s = s + x }; s`, 3},
+		{`let s = 0; for (let x of [1, 2, 3, 4]) { if (x % 2 == 0) { continue }; s = s + x }; s`, 4},
+		{`let x = 0; for (x of [5, 6]) {}; x`, 6},
+		{`let fs = []; for (let i of [1, 2]) { fs[len(fs)] = fn() { i } }; fs[0]() + fs[1]() * 10`, 21},
+		{`let f = fn() { for (let x of [1, 2, 3]) { if (x == 2) { return x } }; 0 }; f()`, 2},
+		{`let a = [1]; let n = 0; for (let x of a) { n = n + 1; if (len(a) < 3) { a[len(a)] = x } }; n`, 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`for (let x of 1) {}`, "INTEGER is not iterable"},
+		{`for (y of [1]) {}`, "identifier not found: y"},
+	}
+
+	for _, tt := range errors {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let g = fn*() { yield 1; yield 2 }(); g.next().value + g.next().value`, 3},
+		{`let g = fn*() { yield 1 }(); g.next(); g.next().done`, true},
+		{`let g = fn*() { yield 1; return 5 }(); g.next(); g.next().value`, 5},
+		{`let g = fn*() { let x = yield 1; yield x * 2 }(); g.next(); g.next(21).value`, 42},
+		{`let count = fn*(n) { let i = 0; while (i < n) { yield i; i = i + 1 } }; let s = 0; for (let x of count(5)) { s = s + x }; s`, 10},
+		{`let nat = fn*() { let n = 0; while (true) { yield n; n = n + 1 } }; let s = 0; for (let n of nat()) { if (n > 4) { break }; s = s + n }; s`, 10},
+		{`let inner = fn*() { yield 1; yield 2; return 3 }; let outer = fn*() { let r = yield* inner(); yield r }; let s = ""; for (let x of outer()) { s = s + x }; s`, "123"},
+		{`let g = fn*() { yield* [1, 2]; yield* "ab" }; let s = ""; for (let x of g()) { s = s + x }; s`, "12ab"},
+		{`let log = ""; let g = fn*() { try { yield 1; yield 2 } finally { log = log + "f" } }; for (let x of g()) { log = log + x; break }; log`, "1f"},
+		{`let g = fn*() { try { yield 1 } catch (e) { yield "caught" } }(); g.next(); let r = g.return(7); "" + r.value + r.done`, "7true"},
+		{`let g = fn*() { yield 1 }(); g.return(1); g.next().done`, true},
+		{`let g = fn*() { try { yield 1 } finally { yield 2 } }(); g.next(); g.return(0).value`, 2},
+		{`let inner = fn*() { try { yield 1 } finally { log = "inner" } }; let log = ""; let outer = fn*() { yield* inner() }; let g = outer(); g.next(); g.return(0); log`, "inner"},
+		{`let obj = {items: [1, 2], each: fn*() { yield* this.items }}; let s = 0; for (let x of obj.each()) { s = s + x }; s`, 3},
+		{`let g = fn*() { yield 1 }(); typeof g`, "object"},
+		{`let started = false; let g = fn*() { started = true; yield 1 }; let it = g(); started`, false},
+		{`let g = fn*() { yield 1; throw "boom" }(); g.next(); try { g.next() } catch (e) { e }`, "boom"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	testErrorObject(t, testEval(`let g = fn*() { g.next() }(); g.next()`), "generator is already running")
+}
+
+func TestIteratorProtocol(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let s = ""; for (let k of {a: 1, b: 2}) { s = s + k }; s`, "ab"},
+		{`let it = {i: 0, next() { this.i = this.i + 1; if (this.i > 3) { return {done: true} }; {value: this.i, done: false} }}; let s = 0; for (let x of it) { s = s + x }; s`, 6},
+		{`let h = {items: [1, 2, 3], [Symbol.iterator]() { this.items[Symbol.iterator]() }}; let s = 0; for (let x of h) { s = s + x }; s`, 6},
+		{`let it = "ab"[Symbol.iterator](); it.next().value + it.next().value`, "ab"},
+		{`let h = {*[Symbol.iterator]() { yield 1; yield 2 }}; let s = 0; for (let x of h) { s = s + x }; s`, 3},
+		{`class Countdown { constructor(n) { this.n = n } *[Symbol.iterator]() { let i = this.n; while (i > 0) { yield i; i = i - 1 } } }; let s = ""; for (let x of new Countdown(3)) { s = s + x }; s`, "321"},
+		{`class Range { constructor(n) { this.n = n; this.i = 0 } next() { this.i = this.i + 1; {value: this.i, done: this.i > this.n} } }; len(toArray(new Range(4)))`, 4},
+		{`let closed = false; let it = {next() { {value: 1, done: false} }, return() { closed = true }}; for (let x of it) { break }; closed`, true},
+		{`let proto = {*[Symbol.iterator]() { yield this.v }}; let h = {__proto__: proto, v: 7}; toArray(h)[0]`, 7},
+		{`let g = fn*() { yield 1 }(); g[Symbol.iterator]() == g`, true},
+		{`let r = range(3); r[Symbol.iterator]() == r`, true},
+		{`let r = range(2); r.next().value + r.next().value`, 1},
+		{`let r = range(1); r.next(); r.next().done`, true},
+		{`typeof range(1)`, "object"},
+		{`Symbol.iterator`, "Symbol.iterator"},
+		{`let g = fn*() { yield* {a: 1, b: 2} }; toArray(g())[1]`, "b"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`for (let x of new (class {})()) {}`, "INSTANCE is not iterable"},
+		{`toArray({[Symbol.iterator]: 1})`, "Symbol.iterator must be a function, got INTEGER"},
+		{`toArray({[Symbol.iterator]() { 1 }})`, "Symbol.iterator must return an iterator, got INTEGER"},
+		{`toArray({next() { 1 }})`, "iterator result must be an object, got INTEGER"},
+		{`class C { [1]() {} }`, "class member name must be STRING, got INTEGER"},
+	}
+
+	for _, tt := range errors {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestLazySequences(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`toArray(range(4))`, []int64{0, 1, 2, 3}},
+		{`toArray(range(2, 5))`, []int64{2, 3, 4}},
+		{`toArray(range(10, 0, -3))`, []int64{10, 7, 4, 1}},
+		{`toArray(range(3, 3))`, []int64{}},
+		{`toArray(range(9223372036854775805, 9223372036854775807, 5))`, []int64{9223372036854775805}},
+		{`toArray(range(9223372036854775805, 9223372036854775807))`, []int64{9223372036854775805, 9223372036854775806}},
+		{`toArray(range(-9223372036854775807 - 1, -9223372036854775807 + 1))`, []int64{-9223372036854775808, -9223372036854775807}},
+		{`toArray(range(-9223372036854775806, -9223372036854775807 - 1, -5))`, []int64{-9223372036854775806}},
+		{`toArray(range(-9223372036854775807 - 1, 9223372036854775807, 9223372036854775807))`, []int64{-9223372036854775808, -1, 9223372036854775806}},
+		{`toArray(range(9223372036854775807, -9223372036854775807 - 1, -9223372036854775807 - 1))`, []int64{9223372036854775807, -1}},
+		{`toArray(map([1, 2, 3], fn(x) { x * 2 }))`, []int64{2, 4, 6}},
+		{`toArray(filter(range(10), fn(x) { x % 3 == 0 }))`, []int64{0, 3, 6, 9}},
+		{`toArray(take(range(1000000000000), 3))`, []int64{0, 1, 2}},
+		{`toArray(take(map(filter(range(1000000000000), fn(x) { x % 2 == 1 }), fn(x) { x * x }), 3))`, []int64{1, 9, 25}},
+		{`toArray(range(1, 100).filter(fn(x) { x % 7 == 0 }).map(fn(x) { x / 7 }).take(3))`, []int64{1, 2, 3}},
+		{`let nat = fn*() { let n = 0; while (true) { yield n; n = n + 1 } }; toArray(take(nat(), 4))`, []int64{0, 1, 2, 3}},
+		{`toArray(take([5, 6], 0))`, []int64{}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		arr, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Errorf("%s: object is not Array. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		expected := tt.expected.([]int64)
+		if len(arr.Elements) != len(expected) {
+			t.Errorf("%s: wrong number of elements. got=%d, want=%d", tt.input, len(arr.Elements), len(expected))
+			continue
+		}
+		for i, want := range expected {
+			testIntegerObject(t, tt.input, arr.Elements[i], want)
+		}
+	}
+
+	// map只在取值时调用函数
+	lazy := testEval(`let calls = 0; let m = map(range(100), fn(x) { calls = calls + 1; x }); m.next(); m.next(); calls`)
+	testIntegerObject(t, "lazy map", lazy, 2)
+
+	// take取完后关闭生成器, 执行它的finally块
+	closed := testEval(`let log = ""; let g = fn*() { try { yield 1; yield 2; yield 3 } finally { log = "closed" } }; toArray(take(g(), 2)); log`)
+	testStringObject(t, "take closes generator", closed, "closed")
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`range(0, 5, 0)`, "range step cannot be zero"},
+		{`range("a")`, "arguments to `range` must be INTEGER, got STRING"},
+		{`map([1], 1)`, "second argument to `map` must be a function, got INTEGER"},
+		{`take([1], -1)`, "second argument to `take` must be a non-negative INTEGER, got -1"},
+		{`toArray(map([1, 0], fn(x) { 1 / x }))`, "division by zero"},
+		{`toArray(5)`, "INTEGER is not iterable"},
+	}
+
+	for _, tt := range errors {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestAbandonedCoroutinesExit(t *testing.T) {
+	tests := []string{
+		// 只取了一个值就丢弃的生成器
+		`let gen = fn*() { yield 1; yield 2 }; let i = 0; while (i < 50) { gen().next(); i = i + 1 }`,
+		// 丢弃的惰性迭代器中的生成器
+		`let gen = fn*() { yield 1; yield 2 }; let i = 0; while (i < 50) { map(gen(), fn(x) { x }).next(); i = i + 1 }`,
+		// yield* 委托中暂停的两层生成器
+		`let inner = fn*() { yield 1; yield 2 }; let outer = fn*() { yield* inner() }; let i = 0; while (i < 25) { outer().next(); i = i + 1 }`,
+		// 等待永远不会敲定的Promise的async函数
+		`let f = async fn() { await new Promise(fn() {}) }; let i = 0; while (i < 50) { f(); i = i + 1 }`,
+	}
+
+	for _, input := range tests {
+		before := runtime.NumGoroutine()
+		testEval(input)
+		if err := RunEventLoop(); err != nil {
+			t.Fatalf("%s: unexpected error: %s", input, err.Inspect())
+		}
+		if !waitForGoroutines(before) {
+			t.Errorf("%s: goroutines still running after the coroutines were dropped. before=%d, now=%d",
+				input, before, runtime.NumGoroutine())
+		}
+	}
+
+	// 只要还能恢复, 即使生成器对象本身已经不可达, 函数体也不会被结束
+	env := object.NewEnvironment()
+	evalIn(`let gen = fn*() { yield 1; yield 2 }; let next = gen().next; next()`, env)
+	runtime.GC()
+	runtime.GC()
+	time.Sleep(10 * time.Millisecond)
+	result := evalIn(`next()["value"]`, env)
+	testIntegerObject(t, "next after GC", result, 2)
+}
+
+// 反复触发GC, 等待被丢弃的生成器的goroutine退出
+func waitForGoroutines(limit int) bool {
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		if runtime.NumGoroutine() <= limit {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestPromises(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`await Promise.resolve(1)`, 1},
+		{`await 5`, 5},
+		{`await new Promise(fn(resolve) { resolve(2) })`, 2},
+		{`await new Promise(fn(resolve, reject) { resolve(1); reject(2); resolve(3) })`, 1},
+		{`await Promise.resolve(1).then(fn(x) { x + 1 }).then(fn(x) { x * 10 })`, 20},
+		{`await Promise.reject(1).catch(fn(e) { e + 1 })`, 2},
+		{`await Promise.reject(1).then(fn(x) { 0 }).catch(fn(e) { e * 3 })`, 3},
+		{`await Promise.resolve(1).then(fn(x) { throw x + 1 }).catch(fn(e) { e })`, 2},
+		{`await Promise.resolve(1).then(fn(x) { Promise.resolve(x + 5) })`, 6},
+		{`let log = ""; await Promise.resolve(1).finally(fn() { log = "f" }).then(fn(x) { log + x })`, "f1"},
+		{`await Promise.reject(1).finally(fn() { 2 }).catch(fn(e) { e })`, 1},
+		{`await new Promise(fn() { throw "bad" }).catch(fn(e) { e })`, "bad"},
+		{`await new Promise(fn() { 1 / 0 }).catch(fn(e) { e.kind })`, "RangeError"},
+		{`await Promise.resolve({then: fn(resolve) { resolve(9) }})`, 9},
+		{`let p = Promise.all([1, Promise.resolve(2), new Promise(fn(r) { setTimeout(r, 1, 3) })]); let a = await p; a[0] + a[1] + a[2]`, 6},
+		{`len(await Promise.all([]))`, 0},
+		{`await Promise.all([1, Promise.reject("x")]).catch(fn(e) { e })`, "x"},
+		{`let r = await Promise.allSettled([1, Promise.reject(2)]); r[0].status + r[1].status + r[1].reason`, "fulfilledrejected2"},
+		{`await Promise.race([new Promise(fn(r) { setTimeout(r, 20, "slow") }), new Promise(fn(r) { setTimeout(r, 1, "fast") })])`, "fast"},
+		{`await Promise.any([Promise.reject(1), Promise.resolve(2)])`, 2},
+		{`let e = await Promise.any([Promise.reject(1), Promise.reject(2)]).catch(fn(e) { e }); e.kind + len(e.errors)`, "AggregateError2"},
+		{`try { await Promise.reject("no") } catch (e) { e }`, "no"},
+		{`typeof Promise.resolve(1)`, "object"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+		if err := RunEventLoop(); err != nil {
+			t.Errorf("%s: unexpected error after the event loop: %s", tt.input, err.Inspect())
+		}
+	}
+
+	inspect := []struct {
+		input    string
+		expected string
+	}{
+		{`Promise.resolve(1)`, "Promise {1}"},
+		{`new Promise(fn() {})`, "Promise {<pending>}"},
+		{`let p = Promise.reject(2); p.catch(fn() {}); p`, "Promise {<rejected> 2}"},
+	}
+
+	for _, tt := range inspect {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong Inspect. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+		RunEventLoop()
+	}
+
+	testErrorObject(t, testEval(`new Promise(1)`), "Promise executor must be a function, got INTEGER")
+	testErrorObject(t, testEval(`await new Promise(fn() {})`), "await on a promise that never settles")
+	testErrorObject(t, testEval(`await Promise.reject("x")`), "uncaught x")
+}
+
+func TestAsyncFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = async fn() { 1 }; await f()`, 1},
+		{`let f = async fn(x) { return x * 2 }; await f(21)`, 42},
+		{`let f = async fn() { let a = await 1; let b = await Promise.resolve(2); a + b }; await f()`, 3},
+		{`let f = async fn() { throw "oops" }; await f().catch(fn(e) { e })`, "oops"},
+		{`let f = async fn() { try { await Promise.reject(1) } catch (e) { e + 10 } }; await f()`, 11},
+		{`let f = async fn() { try { await Promise.reject(1) } finally { 0 } }; await f().catch(fn(e) { "rejected " + e })`, "rejected 1"},
+		{`let sleep = fn(ms) { new Promise(fn(r) { setTimeout(r, ms) }) }; let f = async fn(x) { await sleep(1); x }; let r = await Promise.all([f(1), f(2)]); r[0] + r[1]`, 3},
+		{`let f = async fn(n) { if (n == 0) { return 0 }; n + await f(n - 1) }; await f(4)`, 10},
+		{`let log = ""; let f = async fn() { log = log + "a"; await 0; log = log + "c" }; let p = f(); log = log + "b"; await p; log`, "abc"},
+		{`class Loader { constructor(v) { this.v = v } async load() { await 0; this.v } }; await new Loader(5).load()`, 5},
+		{`let h = {v: 7, async get() { await 0; this.v }}; await h.get()`, 7},
+		{`let f = async fn() { 1 }; typeof f()`, "object"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+		if err := RunEventLoop(); err != nil {
+			t.Errorf("%s: unexpected error after the event loop: %s", tt.input, err.Inspect())
+		}
+	}
+}
+
+func TestEventLoop(t *testing.T) {
+	// 同步代码先执行, 然后是微任务, 最后是定时器
+	input := `
+let log = []
+let add = fn(x) { log[len(log)] = x }
+setTimeout(fn() { add("timeout") })
+Promise.resolve().then(fn() { add("micro 1") }).then(fn() { add("micro 2") })
+let f = async fn() { add("async start"); await 0; add("async resumed") }
+f()
+add("sync")
+log`
+
+	evaluated := testEval(input)
+	if err := RunEventLoop(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Inspect())
+	}
+
+	expected := []string{"async start", "sync", "micro 1", "async resumed", "micro 2", "timeout"}
+	log, ok := evaluated.(*object.Array)
+	if !ok || len(log.Elements) != len(expected) {
+		t.Fatalf("wrong log. got=%s", evaluated.Inspect())
+	}
+	for i, want := range expected {
+		testStringObject(t, "event loop order", log.Elements[i], want)
+	}
+
+	// 定时器按到期时间执行, 被取消的定时器不执行
+	ordered := testEval(`let log = ""; setTimeout(fn() { log = log + "b" }, 5); setTimeout(fn() { log = log + "a" }, 1); let id = setTimeout(fn() { log = log + "x" }, 2); clearTimeout(id); await new Promise(fn(r) { setTimeout(r, 10) }); log`)
+	testStringObject(t, "timers", ordered, "ab")
+
+	unhandled := []struct {
+		input    string
+		expected string
+	}{
+		{`Promise.reject(1)`, "unhandled promise rejection: 1"},
+		{`let f = async fn() { throw Error("boom") }; f()`, "unhandled promise rejection: boom"},
+		{`setTimeout(fn() { 1 / 0 })`, "division by zero"},
+	}
+
+	for _, tt := range unhandled {
+		testEval(tt.input)
+		err := RunEventLoop()
+		if err == nil {
+			t.Errorf("%s: expected an error from the event loop", tt.input)
+			continue
+		}
+		if err.Message != tt.expected {
+			t.Errorf("%s: wrong error. expected=%q, got=%q", tt.input, tt.expected, err.Message)
+		}
+	}
+
+	// 定时器回调出错后不留下任何任务
+	testEval(`setTimeout(fn() { Promise.resolve().then(fn() { 1 }); Promise.reject(2); throw "boom" }); setTimeout(fn() { 3 }, 50)`)
+	if err := RunEventLoop(); err == nil || err.Message != "uncaught boom" {
+		t.Fatalf("expected the timer error, got=%v", err)
+	}
+	if len(microtasks) != 0 || len(timers) != 0 || len(unhandledRejections) != 0 {
+		t.Errorf("work left after a timer error. microtasks=%d, timers=%d, rejections=%d",
+			len(microtasks), len(timers), len(unhandledRejections))
+	}
+
+	// 稍后登记的处理函数使拒绝不再被报告
+	testEval(`let p = Promise.reject(1); setTimeout(fn() { p.catch(fn() {}) })`)
+	if err := RunEventLoop(); err != nil {
+		t.Errorf("handled rejection reported: %s", err.Inspect())
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { throw 1 } catch (e) { e + 1 }`, 2},
+		{`try { throw "x" } catch (e) { e }`, "x"},
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`let f = fn() { throw {code: 7} }; try { f() } catch (e) { e.code }`, 7},
+		{`try { throw 1 } catch { "caught" }`, "caught"},
+		{`let log = ""; try { log = log + "t" } finally { log = log + "f" }; log`, "tf"},
+		{`let log = ""; try { throw 1 } catch (e) { log = log + "c" } finally { log = log + "f" }; log`, "cf"},
+		{`let log = ""; try { try { throw 1 } finally { log = log + "inner" } } catch (e) { log = log + "outer" }; log`, "innerouter"},
+		{`let f = fn() { try { return 1 } finally { 2 } }; f()`, 1},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, 2},
+		{`let f = fn() { try { throw 1 } finally { return 2 } }; f()`, 2},
+		{`let log = ""; let i = 0; while (true) { try { i = i + 1; break } finally { log = log + "f" } }; log + i`, "f1"},
+		{`let i = 0; while (i < 3) { try { i = i + 1; continue } finally { i = i + 10 } }; i`, 11},
+		{`let f = fn() { while (true) { try { return 1 } finally { break } }; 2 }; f()`, 2},
+		{`try { throw 1 } catch (e) { throw e + 1 } finally { 0 }`, nil},
+		{`try { try { throw 1 } catch (e) { throw e + 1 } } catch (e) { e }`, 2},
+		{`try { throw Error("boom") } catch (e) { e.message + "/" + e.kind }`, "boom/Error"},
+		{`try { throw Error("boom", "MyError") } catch (e) { e.kind }`, "MyError"},
+		{`try { 1 / 0 } catch (e) { e.kind + ": " + e.message }`, "RangeError: division by zero"},
+		{`try { missing } catch (e) { e.kind }`, "ReferenceError"},
+		{`try { 1 + true } catch (e) { e.kind }`, "TypeError"},
+		{`try { [1][5] = 1 } catch (e) { e.kind }`, "RangeError"},
+		{`try { len(1) } catch (e) { e.kind + ": " + e.message }`, "BuiltinError: argument to `len` not supported, got INTEGER"},
+		{`try {
+  1 / 0
+} catch (e) { e.line * 100 + e.column }`, 205},
+		{`try { 1 / 0 } catch (e) { typeof e }`, "object"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if tt.expected == nil {
+			if !isError(evaluated) {
+				t.Errorf("%s: expected an uncaught error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+			continue
+		}
+		testExpectedObject(t, tt.input, evaluated, tt.expected)
+	}
+
+	uncaught := []struct {
+		input    string
+		expected string
+	}{
+		{`throw 42`, "uncaught 42"},
+		{`throw "oops"`, "uncaught oops"},
+		{`throw Error("fatal")`, "fatal"},
+		{`try { 1 / 0 } catch (e) { throw e }`, "division by zero"},
+	}
+
+	for _, tt := range uncaught {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+
+	err := testEval(`try { 1 / 0 } catch (e) { throw e }`).(*object.Error)
+	if err.Kind != object.RANGE_ERROR {
+		t.Errorf("rethrown error has wrong kind. got=%q", err.Kind)
+	}
+}
+
+func TestStackTraces(t *testing.T) {
+	input := `let inner = fn(x) { x / 0 }
+let outer = fn() { inner(1) }
+class A { m() { outer() } }
+new A().m()`
+
+	err, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("expected an error")
+	}
+
+	expected := []struct {
+		function     string
+		line, column int
+	}{
+		{"A.m", 4, 10},
+		{"outer", 3, 22},
+		{"inner", 2, 25},
+	}
+
+	if len(err.Stack) != len(expected) {
+		t.Fatalf("wrong stack depth. expected=%d, got=%d (%+v)", len(expected), len(err.Stack), err.Stack)
+	}
+	for i, frame := range expected {
+		if err.Stack[i].Function != frame.function || err.Stack[i].Pos.Line != frame.line || err.Stack[i].Pos.Column != frame.column {
+			t.Errorf("stack[%d] wrong. expected=%+v, got=%+v", i, frame, err.Stack[i])
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn() { 1 / 0 }\ntry { f() } catch (e) { e.stack }",
+			"RangeError: division by zero\n    at f (1:18)\n    at <main> (2:8)"},
+		{"let h = {go() { throw Error(\"bad\") }}\ntry { h.go() } catch (e) { e.stack }",
+			"Error: bad\n    at go (1:17)\n    at <main> (2:11)"},
+		{"try { 1 / 0 } catch (e) { e.stack }", "RangeError: division by zero\n    at <main> (1:9)"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+
+	// 出错后调用栈恢复为空
+	testEval(input)
+	if len(callStack) != 0 {
+		t.Errorf("call stack not unwound after an error. got=%+v", callStack)
+	}
+	if err := testEval("1 / 0").(*object.Error); len(err.Stack) != 0 {
+		t.Errorf("top-level error should have no frames. got=%+v", err.Stack)
+	}
+}
+
+// 在给定的环境中求值, 用于分几次执行的测试
+func evalIn(input string, env *object.Environment) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	return Eval(program, env)
+}
+
+func testExpectedObject(t *testing.T, input string, obj object.Object, expected interface{}) bool {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		return testIntegerObject(t, input, obj, int64(expected))
+	case float64:
+		return testFloatObject(t, input, obj, expected)
+	case string:
+		return testStringObject(t, input, obj, expected)
+	case bool:
+		return testBooleanObject(t, input, obj, expected)
+	default:
+		t.Errorf("%s: type of expected not handled. got=%T", input, expected)
+		return false
+	}
+}
+
+func testIntegerObject(t *testing.T, input string, obj object.Object, expected int64) bool {
+	t.Helper()
+
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("%s: object is not Integer. got=%T (%+v)", input, obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("%s: object has wrong value. got=%d, want=%d", input, result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testFloatObject(t *testing.T, input string, obj object.Object, expected float64) bool {
+	t.Helper()
+
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("%s: object is not Float. got=%T (%+v)", input, obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("%s: object has wrong value. got=%g, want=%g", input, result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testStringObject(t *testing.T, input string, obj object.Object, expected string) bool {
+	t.Helper()
+
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("%s: object is not String. got=%T (%+v)", input, obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("%s: object has wrong value. got=%q, want=%q", input, result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testBooleanObject(t *testing.T, input string, obj object.Object, expected bool) bool {
+	t.Helper()
+
+	result, ok := obj.(*object.Boolean)
+	if !ok {
+		t.Errorf("%s: object is not Boolean. got=%T (%+v)", input, obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("%s: object has wrong value. got=%t, want=%t", input, result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	t.Helper()
+
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("no error object returned. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		return false
+	}
+
+	return true
+}
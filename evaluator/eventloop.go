@@ -0,0 +1,216 @@
+package evaluator
+
+/*
+	事件循环
+	单线程执行: 先执行完所有微任务, 再执行到期最早的定时器回调, 直到没有剩余的工作
+	Promise的回调是微任务, setTimeout的回调是宏任务
+	运行器和REPL在执行完顶层代码后调用RunEventLoop, 顶层的await也会在原地运行事件循环
+*/
+
+import (
+	"finger/object"
+	"finger/token"
+	"strings"
+	"time"
+)
+
+// 定时器
+type timer struct {
+	id int64
+	due time.Time
+	fn object.Object
+	args []object.Object
+}
+
+var (
+	microtasks []func() // 微任务队列
+	timers []*timer // 未到期的定时器, 按创建顺序排列
+	nextTimerID int64
+	unhandledRejections []*object.Promise // 拒绝时还没有处理函数的Promise
+)
+
+// 定时器回调是用户函数, 在init中注册以避免初始化循环
+func init() {
+	builtins["setTimeout"] = &object.Builtin{Fn: setTimeoutBuiltin}
+	builtins["clearTimeout"] = &object.Builtin{Fn: clearTimeoutBuiltin}
+}
+
+func enqueueMicrotask(task func()) {
+	microtasks = append(microtasks, task)
+}
+
+/*
+	执行下一个任务, 没有剩余的任务时返回false
+	定时器回调中未捕获的错误作为结果返回
+*/
+func runNextTask() (bool, *object.Error) {
+	if len(microtasks) > 0 {
+		task := microtasks[0]
+		microtasks = microtasks[1:]
+		task()
+		return true, nil
+	}
+
+	if len(timers) == 0 {
+		return false, nil
+	}
+
+	// 到期时间相同的定时器按创建顺序执行
+	next := 0
+	for i, t := range timers {
+		if t.due.Before(timers[next].due) {
+			next = i
+		}
+	}
+	t := timers[next]
+	timers = append(timers[:next], timers[next+1:]...)
+
+	if wait := time.Until(t.due); wait > 0 {
+		time.Sleep(wait)
+	}
+	if result := applyFunction(t.fn, t.args, token.Position{}); isError(result) {
+		return true, result.(*object.Error)
+	}
+	return true, nil
+}
+
+/*
+	运行事件循环直到没有剩余的工作
+	返回定时器回调中未捕获的错误, 或者第一个没有被处理的Promise拒绝
+	定时器回调出错时丢弃剩余的任务, 以免它们在REPL的下一次输入之后才执行
+*/
+func RunEventLoop() *object.Error {
+	for {
+		ran, err := runNextTask()
+		if err != nil {
+			microtasks = nil
+			timers = nil
+			unhandledRejections = nil
+			return err
+		}
+		if !ran {
+			break
+		}
+	}
+
+	return takeUnhandledRejection()
+}
+
+/*
+	运行事件循环直到Promise敲定, 用于顶层的await
+*/
+func runUntilSettled(promise *object.Promise) *object.Error {
+	for promise.State == object.PENDING {
+		ran, err := runNextTask()
+		if err != nil {
+			return err
+		}
+		if !ran {
+			return newError("await on a promise that never settles")
+		}
+	}
+
+	return nil
+}
+
+func trackRejection(promise *object.Promise) {
+	unhandledRejections = append(unhandledRejections, promise)
+}
+
+/*
+	取出第一个直到现在仍没有处理函数的拒绝, 并清空记录
+*/
+func takeUnhandledRejection() *object.Error {
+	rejections := unhandledRejections
+	unhandledRejections = nil
+
+	for _, promise := range rejections {
+		if !promise.Handled {
+			err := thrownError(promise.Value, rejectionPosition(promise.Value))
+			err.Message = "unhandled promise rejection: " + strings.TrimPrefix(err.Message, "uncaught ")
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+	setTimeout(fn, delay, ...args) 在至少delay毫秒之后调用fn, 返回定时器的编号
+*/
+func setTimeoutBuiltin(args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	if !isCallable(args[0]) {
+		return newError("first argument to `setTimeout` must be a function, got %s", args[0].Type())
+	}
+
+	delay := int64(0)
+	if len(args) > 1 {
+		ms, ok := args[1].(*object.Integer)
+		if !ok {
+			return newError("second argument to `setTimeout` must be INTEGER, got %s", args[1].Type())
+		}
+		if ms.Value > 0 {
+			delay = ms.Value
+		}
+	}
+
+	var extra []object.Object
+	if len(args) > 2 {
+		extra = args[2:]
+	}
+
+	nextTimerID++
+	timers = append(timers, &timer{
+		id: nextTimerID,
+		due: time.Now().Add(time.Duration(delay) * time.Millisecond),
+		fn: args[0],
+		args: extra,
+	})
+
+	return &object.Integer{Value: nextTimerID}
+}
+
+/*
+	clearTimeout(id) 取消还没有执行的定时器
+*/
+func clearTimeoutBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	id, ok := args[0].(*object.Integer)
+	if !ok {
+		return UNDEFINED
+	}
+	for i, t := range timers {
+		if t.id == id.Value {
+			timers = append(timers[:i], timers[i+1:]...)
+			break
+		}
+	}
+
+	return UNDEFINED
+}
+
+/*
+	拒绝原因是已经抛出过的错误对象时, 返回它记录的出错位置
+*/
+func rejectionPosition(reason object.Object) token.Position {
+	hash, ok := reason.(*object.Hash)
+	if !ok {
+		return token.Position{}
+	}
+
+	line, _ := hash.Get(&object.String{Value: "line"})
+	column, _ := hash.Get(&object.String{Value: "column"})
+	l, ok1 := line.(*object.Integer)
+	c, ok2 := column.(*object.Integer)
+	if !ok1 || !ok2 {
+		return token.Position{}
+	}
+
+	return token.Position{Line: int(l.Value), Column: int(c.Value)}
+}
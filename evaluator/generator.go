@@ -0,0 +1,322 @@
+package evaluator
+
+/*
+	生成器
+	求值器是递归的, 无法在yield处保存执行状态, 因此每个生成器的函数体在单独的goroutine中执行
+	调用者和生成器通过通道交替执行, 任何时刻只有一方在运行, 所以调用栈等全局状态不需要加锁
+	yield把产出的值交给调用者, 然后等待下一次next或return
+	暂停后不再被引用的生成器由generatorHandle的终结器结束goroutine, 见generatorHandle
+	return使暂停处的yield返回一个不能被catch捕获的错误, 函数体像执行了return一样向外结束, 途中的finally块照常执行
+	async函数使用同样的机制, 只是暂停点是await, 并由Promise的回调恢复执行
+*/
+
+import (
+	"finger/ast"
+	"finger/object"
+	"finger/token"
+	"runtime"
+)
+
+// 发给生成器的恢复信号
+type generatorSignal struct {
+	value object.Object // next的参数, return的返回值, 或要抛出的值
+	close bool // 是否从暂停处提前返回
+	throw bool // 是否在暂停处抛出value, 用于await被拒绝的Promise
+}
+
+// 生成器交给调用者的结果
+type generatorResult struct {
+	value object.Object
+	done bool
+}
+
+/*
+	生成器的执行状态
+*/
+type generatorRun struct {
+	fn *object.Function
+	env *object.Environment
+	name string // 调用栈中显示的函数名
+	callSite token.Position // 创建生成器的位置
+
+	started bool
+	running bool
+	done bool
+	signals chan generatorSignal
+	results chan generatorResult
+	abandoned chan struct{} // 外部引用被回收时关闭
+}
+
+/*
+	创建生成器的执行状态, 函数体中的暂停点通过环境中名为hook的内置函数实现
+	hook是关键字yield或await, 不会与用户定义的变量冲突
+*/
+func newGeneratorRun(fn *object.Function, env *object.Environment, name string, callSite token.Position, hook string) *generatorRun {
+	run := &generatorRun{
+		fn: fn,
+		env: env,
+		name: name,
+		callSite: callSite,
+		signals: make(chan generatorSignal),
+		results: make(chan generatorResult),
+		abandoned: make(chan struct{}),
+	}
+
+	env.Set(hook, run)
+
+	return run
+}
+
+// generatorRun只保存在函数环境中的yield或await下, 用户代码无法访问它
+func (run *generatorRun) Type() object.ObjectType {
+	return "GENERATOR_RUN"
+}
+
+func (run *generatorRun) Inspect() string {
+	return "[generator run " + run.name + "]"
+}
+
+/*
+	创建生成器, 函数体在第一次调用next时才开始执行
+*/
+func newGenerator(fn *object.Function, env *object.Environment, name string, callSite token.Position) *object.Generator {
+	run := newGeneratorRun(fn, env, name, callSite, "yield")
+
+	handle := newGeneratorHandle(run)
+
+	return &object.Generator{
+		Name: fn.Name,
+		Resume: func(value object.Object) (object.Object, bool) {
+			return handle.resume(generatorSignal{value: value})
+		},
+		Close: func(value object.Object) (object.Object, bool) {
+			return handle.resume(generatorSignal{value: value, close: true})
+		},
+	}
+}
+
+/*
+	生成器的外部引用, 只由能够恢复生成器的一方持有: *object.Generator的方法, 或者async函数等待的Promise的回调
+	函数体所在的goroutine不引用它, 所以它不可达时暂停中的函数体再也不会被恢复,
+	这时由终结器通知goroutine直接退出, 释放它持有的环境
+	函数体自己的环境引用着它时(如生成器保存在外层变量中), 它会一直可达, 与其他被引用的值一样
+*/
+type generatorHandle struct {
+	run *generatorRun
+}
+
+func newGeneratorHandle(run *generatorRun) *generatorHandle {
+	handle := &generatorHandle{run: run}
+	runtime.SetFinalizer(handle, func(handle *generatorHandle) {
+		close(handle.run.abandoned)
+	})
+
+	return handle
+}
+
+/*
+	通过外部引用恢复生成器, 恢复期间保持引用可达, 避免终结器在函数体运行时触发
+*/
+func (handle *generatorHandle) resume(signal generatorSignal) (object.Object, bool) {
+	value, done := handle.run.resume(signal)
+	runtime.KeepAlive(handle)
+
+	return value, done
+}
+
+/*
+	恢复生成器并等待它产出下一个值或结束
+	第一次next的参数没有yield接收, 会被忽略
+*/
+func (run *generatorRun) resume(signal generatorSignal) (object.Object, bool) {
+	if run.running {
+		return newTypeError("generator is already running"), true
+	}
+	if run.done || (!run.started && signal.close) {
+		run.done = true
+		if signal.close {
+			return signal.value, true
+		}
+		return UNDEFINED, true
+	}
+
+	run.running = true
+	if run.started {
+		run.signals <- signal
+	} else {
+		run.started = true
+		go run.execute()
+	}
+	result := <-run.results
+	run.running = false
+
+	if result.done {
+		run.done = true
+	}
+	return result.value, result.done
+}
+
+/*
+	在goroutine中执行生成器的函数体, 函数体执行期间调用栈中有生成器的一帧
+*/
+func (run *generatorRun) execute() {
+	pushFrame(run.name, run.callSite)
+	evaluated := Eval(run.fn.Body, run.env)
+	popFrame()
+
+	// 与普通函数一样, 没有return时结果是最后一个表达式的值
+	var value object.Object
+	switch result := evaluated.(type) {
+	case nil:
+		value = UNDEFINED
+	case *object.Error:
+		value = result
+		if result.Uncatchable {
+			value = result.Value
+		}
+	case *object.ReturnValue:
+		value = result.Value
+	default:
+		value = result
+	}
+
+	run.results <- generatorResult{value: value, done: true}
+}
+
+/*
+	产出一个值并暂停, 恢复后返回next的参数, 或者提前返回、抛出错误
+	暂停期间生成器的一帧不在调用栈中
+*/
+func (run *generatorRun) yield(value object.Object, pos token.Position) object.Object {
+	popFrame()
+	run.results <- generatorResult{value: value}
+
+	var signal generatorSignal
+	select {
+	case signal = <-run.signals:
+	case <-run.abandoned:
+		// 没有人能再恢复它, 直接结束goroutine, 不执行finally块等任何用户代码
+		runtime.Goexit()
+	}
+	pushFrame(run.name, run.callSite)
+
+	switch {
+	case signal.close:
+		return &object.Error{Message: "generator closed", Value: signal.value, Uncatchable: true}
+	case signal.throw:
+		return thrownError(signal.value, pos)
+	default:
+		return signal.value
+	}
+}
+
+/*
+	yield表达式求值
+*/
+func evalYieldExpression(node *ast.YieldExpression, env *object.Environment) object.Object {
+	value := object.Object(UNDEFINED)
+	if node.Value != nil {
+		value = Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+	}
+
+	hook, ok := env.Get("yield")
+	if !ok {
+		return withPosition(newError("yield outside of a generator function"), node.Token)
+	}
+	run := hook.(*generatorRun)
+
+	if !node.Delegate {
+		return run.yield(value, node.Token.Pos)
+	}
+
+	return withPosition(evalYieldDelegate(value, run, node.Token.Pos), node.Token)
+}
+
+/*
+	yield* 依次产出可迭代对象中的每个值, 值为委托的生成器的返回值
+	next的参数转交给委托的生成器, 提前返回时也先关闭它
+*/
+func evalYieldDelegate(iterable object.Object, run *generatorRun, pos token.Position) object.Object {
+	inner, ok := iterable.(*object.Generator)
+	if !ok {
+		iter, err := getIterator(iterable)
+		if err != nil {
+			return err
+		}
+		for {
+			value, done := iter.Next()
+			if isError(value) {
+				return value
+			}
+			if done {
+				return UNDEFINED
+			}
+			sent := run.yield(value, pos)
+			if err, ok := sent.(*object.Error); ok {
+				if err.Uncatchable {
+					if closeErr := iter.Stop(); closeErr != nil {
+						return closeErr
+					}
+				}
+				return err
+			}
+		}
+	}
+
+	sent := object.Object(UNDEFINED)
+	for {
+		value, done := inner.Resume(sent)
+		if isError(value) || done {
+			return value
+		}
+		sent = run.yield(value, pos)
+		if err, ok := sent.(*object.Error); ok {
+			if err.Uncatchable {
+				if result, _ := inner.Close(err.Value); isError(result) {
+					return result
+				}
+			}
+			return err
+		}
+	}
+}
+
+/*
+	生成器的next和return方法, 返回 {value, done} 形式的迭代结果
+	生成器本身也是可迭代的, Symbol.iterator 方法返回它自己
+*/
+func generatorMethod(gen *object.Generator, name string) object.Object {
+	var step func(object.Object) (object.Object, bool)
+	switch name {
+	case "next":
+		step = gen.Resume
+	case "return":
+		step = gen.Close
+	case token.ITERATOR:
+		return returnSelf(gen)
+	default:
+		return UNDEFINED
+	}
+
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		arg := object.Object(UNDEFINED)
+		if len(args) > 0 {
+			arg = args[0]
+		}
+		value, done := step(arg)
+		if isError(value) {
+			return value
+		}
+		return iteratorResult(value, done)
+	}}
+}
+
+func iteratorResult(value object.Object, done bool) *object.Hash {
+	hash := object.NewHash()
+	hash.Set(&object.String{Value: "value"}, value)
+	hash.Set(&object.String{Value: "done"}, nativeBoolToBooleanObject(done))
+	return hash
+}
@@ -0,0 +1,427 @@
+package evaluator
+
+/*
+	迭代器协议与for...of循环
+	getIterator为可迭代的值创建*object.Iterator, for...of、yield*和迭代器相关的内置函数都通过它迭代
+	用户对象按以下顺序查找迭代方式:
+	1. Symbol.iterator 方法, 它返回的迭代器
+	2. 对象自身的next方法, 即对象本身就是迭代器
+	3. 普通的哈希表按插入顺序迭代它的键
+	range、map、filter和take返回的迭代器是惰性的, 每次只计算下一个值, 不会创建中间数组
+*/
+
+import (
+	"finger/ast"
+	"finger/object"
+	"finger/token"
+)
+
+// 迭代器相关的内置函数会调用用户函数, 在init中注册以避免初始化循环
+func init() {
+	symbol := object.NewHash()
+	symbol.Set(&object.String{Value: "iterator"}, &object.String{Value: token.ITERATOR})
+	symbol.Frozen = true
+	globals["Symbol"] = symbol
+
+	builtins["range"] = &object.Builtin{Fn: rangeBuiltin}
+	builtins["map"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return adapterBuiltin(args, mapIterator)
+	}}
+	builtins["filter"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return adapterBuiltin(args, filterIterator)
+	}}
+	builtins["take"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return adapterBuiltin(args, takeIterator)
+	}}
+	builtins["toArray"] = &object.Builtin{Fn: toArrayBuiltin}
+}
+
+/*
+	为可迭代的值创建迭代器
+*/
+func getIterator(obj object.Object) (*object.Iterator, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Iterator:
+		return obj, nil
+	case *object.Generator:
+		return generatorIterator(obj), nil
+	case *object.Array:
+		return object.NewArrayIterator(obj), nil
+	case *object.String:
+		return object.NewStringIterator(obj), nil
+	case *object.Hash, *object.Instance:
+		return userIterator(obj)
+	default:
+		return nil, newTypeError("%s is not iterable", obj.Type())
+	}
+}
+
+func generatorIterator(gen *object.Generator) *object.Iterator {
+	return &object.Iterator{
+		Name: "generator",
+		Next: func() (object.Object, bool) {
+			return gen.Resume(UNDEFINED)
+		},
+		Close: func() object.Object {
+			if result, _ := gen.Close(UNDEFINED); isError(result) {
+				return result
+			}
+			return nil
+		},
+	}
+}
+
+/*
+	用户对象的迭代器
+*/
+func userIterator(obj object.Object) (*object.Iterator, *object.Error) {
+	if hook := getProperty(obj, token.ITERATOR); hook != UNDEFINED {
+		if !isCallable(hook) {
+			return nil, newTypeError("Symbol.iterator must be a function, got %s", hook.Type())
+		}
+		result := applyFunction(hook, []object.Object{}, token.Position{})
+		if err, ok := result.(*object.Error); ok {
+			return nil, err
+		}
+		switch result := result.(type) {
+		case *object.Iterator, *object.Generator:
+			return getIterator(result)
+		}
+		if iter, ok := protocolIterator(result); ok {
+			return iter, nil
+		}
+		return nil, newTypeError("Symbol.iterator must return an iterator, got %s", result.Type())
+	}
+
+	if iter, ok := protocolIterator(obj); ok {
+		return iter, nil
+	}
+
+	if hash, ok := obj.(*object.Hash); ok {
+		return object.NewHashIterator(hash), nil
+	}
+
+	return nil, newTypeError("%s is not iterable", obj.Type())
+}
+
+/*
+	包装提供next方法的用户对象, next返回 {value, done} 形式的迭代结果
+	提前结束时如果对象有return方法则调用它
+*/
+func protocolIterator(obj object.Object) (*object.Iterator, bool) {
+	switch obj.(type) {
+	case *object.Hash, *object.Instance:
+	default:
+		return nil, false
+	}
+
+	next := getProperty(obj, "next")
+	if !isCallable(next) {
+		return nil, false
+	}
+
+	return &object.Iterator{
+		Name: "next",
+		Next: func() (object.Object, bool) {
+			result := applyFunction(next, []object.Object{}, token.Position{})
+			if isError(result) {
+				return result, true
+			}
+			switch result.(type) {
+			case *object.Hash, *object.Instance:
+			default:
+				return newTypeError("iterator result must be an object, got %s", result.Type()), true
+			}
+			return getProperty(result, "value"), isTruthy(getProperty(result, "done"))
+		},
+		Close: func() object.Object {
+			ret := getProperty(obj, "return")
+			if !isCallable(ret) {
+				return nil
+			}
+			if result := applyFunction(ret, []object.Object{}, token.Position{}); isError(result) {
+				return result
+			}
+			return nil
+		},
+	}, true
+}
+
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.BoundMethod, *object.Builtin:
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+	返回一个总是返回obj的内置函数, 用作迭代器自身的 Symbol.iterator 方法
+*/
+func returnSelf(obj object.Object) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return obj
+	}}
+}
+
+func isIteratorKey(obj object.Object) bool {
+	str, ok := obj.(*object.String)
+	return ok && str.Value == token.ITERATOR
+}
+
+/*
+	数组和字符串的 Symbol.iterator 方法, 每次调用返回一个新的迭代器
+*/
+func iteratorHook(obj object.Object) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		iter, err := getIterator(obj)
+		if err != nil {
+			return err
+		}
+		return iter
+	}}
+}
+
+/*
+	迭代器的方法: next、return, 以及可以链式调用的map、filter和take
+*/
+func iteratorMethod(iter *object.Iterator, name string) object.Object {
+	switch name {
+	case "next":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			value, done := iter.Next()
+			if isError(value) {
+				return value
+			}
+			if value == nil {
+				value = UNDEFINED
+			}
+			return iteratorResult(value, done)
+		}}
+	case "return":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if err := iter.Stop(); err != nil {
+				return err
+			}
+			value := object.Object(UNDEFINED)
+			if len(args) > 0 {
+				value = args[0]
+			}
+			return iteratorResult(value, true)
+		}}
+	case token.ITERATOR:
+		return returnSelf(iter)
+	case "map", "filter", "take":
+		adapters := map[string]func(*object.Iterator, object.Object) object.Object{
+			"map": mapIterator,
+			"filter": filterIterator,
+			"take": takeIterator,
+		}
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return adapterBuiltin(append([]object.Object{iter}, args...), adapters[name])
+		}}
+	default:
+		return UNDEFINED
+	}
+}
+
+/*
+	range(end)、range(start, end)、range(start, end, step)
+	惰性地产生从start开始、不包括end的整数, step为负数时递减
+*/
+func rangeBuiltin(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newError("wrong number of arguments. got=%d, want=1..3", len(args))
+	}
+
+	bounds := []int64{0, 0, 1}
+	for i, arg := range args {
+		n, ok := arg.(*object.Integer)
+		if !ok {
+			return newError("arguments to `range` must be INTEGER, got %s", arg.Type())
+		}
+		bounds[i] = n.Value
+	}
+	if len(args) == 1 {
+		bounds[0], bounds[1] = 0, bounds[0]
+	}
+
+	current, end, step := bounds[0], bounds[1], bounds[2]
+	if step == 0 {
+		return newRangeError("range step cannot be zero")
+	}
+
+	done := false
+	return &object.Iterator{Name: "range", Next: func() (object.Object, bool) {
+		if done || (step > 0 && current >= end) || (step < 0 && current <= end) {
+			return nil, true
+		}
+		value := current
+		// 步长不小于到end的距离时这就是最后一个值, 不再计算current+step, 以免在int64的边界附近溢出后回绕
+		// 距离可能超出int64的范围, 因此按uint64计算
+		if step > 0 {
+			done = uint64(step) >= uint64(end)-uint64(current)
+		} else {
+			done = -uint64(step) >= uint64(current)-uint64(end)
+		}
+		if !done {
+			current += step
+		}
+		return &object.Integer{Value: value}, false
+	}}
+}
+
+/*
+	map(iterable, fn)、filter(iterable, fn)、take(iterable, n) 的公共部分: 检查参数并取得源迭代器
+*/
+func adapterBuiltin(args []object.Object, adapter func(*object.Iterator, object.Object) object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	source, err := getIterator(args[0])
+	if err != nil {
+		return err
+	}
+
+	return adapter(source, args[1])
+}
+
+/*
+	惰性地对每个值调用fn
+*/
+func mapIterator(source *object.Iterator, fn object.Object) object.Object {
+	if !isCallable(fn) {
+		return newError("second argument to `map` must be a function, got %s", fn.Type())
+	}
+
+	return &object.Iterator{Name: "map", Close: source.Stop, Next: func() (object.Object, bool) {
+		value, done := source.Next()
+		if done || isError(value) {
+			return value, true
+		}
+		result := applyFunction(fn, []object.Object{value}, token.Position{})
+		return result, isError(result)
+	}}
+}
+
+/*
+	惰性地跳过fn返回假值的值
+*/
+func filterIterator(source *object.Iterator, fn object.Object) object.Object {
+	if !isCallable(fn) {
+		return newError("second argument to `filter` must be a function, got %s", fn.Type())
+	}
+
+	return &object.Iterator{Name: "filter", Close: source.Stop, Next: func() (object.Object, bool) {
+		for {
+			value, done := source.Next()
+			if done || isError(value) {
+				return value, true
+			}
+			keep := applyFunction(fn, []object.Object{value}, token.Position{})
+			if isError(keep) {
+				return keep, true
+			}
+			if isTruthy(keep) {
+				return value, false
+			}
+		}
+	}}
+}
+
+/*
+	只产生前n个值, 取完后关闭源迭代器, 因此可以安全地用于无限序列
+*/
+func takeIterator(source *object.Iterator, limit object.Object) object.Object {
+	n, ok := limit.(*object.Integer)
+	if !ok || n.Value < 0 {
+		return newError("second argument to `take` must be a non-negative INTEGER, got %s", limit.Inspect())
+	}
+
+	remaining := n.Value
+	return &object.Iterator{Name: "take", Close: source.Stop, Next: func() (object.Object, bool) {
+		if remaining <= 0 {
+			if err := source.Stop(); err != nil {
+				return err, true
+			}
+			return nil, true
+		}
+		remaining--
+		return source.Next()
+	}}
+}
+
+/*
+	toArray(iterable) 把可迭代的值的所有元素收集到新数组中
+*/
+func toArrayBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	iter, err := getIterator(args[0])
+	if err != nil {
+		return err
+	}
+
+	elements := []object.Object{}
+	for {
+		value, done := iter.Next()
+		if isError(value) {
+			return value
+		}
+		if done {
+			return &object.Array{Elements: elements}
+		}
+		elements = append(elements, value)
+	}
+}
+
+/*
+	for...of循环求值
+	使用let时每次迭代都在新的环境中声明循环变量, 循环体中创建的闭包各自捕获当次迭代的值
+	循环被break、return或错误提前结束时关闭迭代器
+*/
+func evalForOfStatement(node *ast.ForOfStatement, env *object.Environment) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	iter, err := getIterator(iterable)
+	if err != nil {
+		return withPosition(err, node.Token)
+	}
+
+	for {
+		value, done := iter.Next()
+		if isError(value) {
+			return withPosition(value, node.Token)
+		}
+		if done {
+			return NULL
+		}
+
+		loopEnv := object.NewEnclosedEnvironment(env)
+		if node.Declare {
+			loopEnv.Set(node.Name.Value, value)
+		} else if !env.Assign(node.Name.Value, value) {
+			iter.Stop()
+			return withPosition(newReferenceError("identifier not found: "+node.Name.Value), node.Name.Token)
+		}
+
+		result := Eval(node.Body, loopEnv)
+		switch result.(type) {
+		case *object.Break, *object.ReturnValue, *object.Error:
+			if closeErr := iter.Stop(); closeErr != nil {
+				return closeErr
+			}
+			if result.Type() == object.BREAK_OBJ {
+				return NULL
+			}
+			return result
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package evaluator
+
+/*
+	整数溢出检查
+	默认情况下Integer运算与Go的int64一样在溢出时回绕;
+	宿主程序可以修改DefaultOverflowMode, 源文件也可以通过开头的编译指示单独设置:
+		"use checked";  溢出时抛出运行时错误
+		"use bigint";   溢出时自动提升为BigInt
+		"use wrapping"; 溢出时回绕
+*/
+
+import (
+	"finger/ast"
+	"finger/object"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+type OverflowMode int
+
+const (
+	OverflowWrap    OverflowMode = iota // 回绕
+	OverflowError                       // 抛出运行时错误
+	OverflowPromote                     // 提升为BigInt
+)
+
+// 未设置编译指示时使用的溢出处理方式
+var DefaultOverflowMode = OverflowWrap
+
+const overflowPragma = "overflow"
+
+// 编译指示与溢出处理方式的对应关系
+var overflowDirectives = map[string]OverflowMode{
+	"use wrapping": OverflowWrap,
+	"use checked":  OverflowError,
+	"use bigint":   OverflowPromote,
+}
+
+var overflowModeNames = map[OverflowMode]string{
+	OverflowWrap:    "wrap",
+	OverflowError:   "error",
+	OverflowPromote: "promote",
+}
+
+func (m OverflowMode) String() string {
+	return overflowModeNames[m]
+}
+
+/*
+	根据名称(wrap | error | promote)解析溢出处理方式
+*/
+func ParseOverflowMode(name string) (OverflowMode, error) {
+	for mode, modeName := range overflowModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+
+	return OverflowWrap, fmt.Errorf("unknown overflow mode %q, want wrap, error or promote", name)
+}
+
+/*
+	处理程序开头的编译指示(由字符串字面量组成的表达式语句)
+*/
+func applyDirectives(program *ast.Program, env *object.Environment) {
+	for _, stmt := range program.Statements {
+		exprStmt, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			return
+		}
+		str, ok := exprStmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			return
+		}
+		if mode, ok := overflowDirectives[str.Value]; ok {
+			env.SetPragma(overflowPragma, mode)
+		}
+	}
+}
+
+/*
+	获取当前环境中的溢出处理方式
+	编译指示在程序入口解析一次, 随环境传给函数和块, 这里只查一次map
+*/
+func overflowMode(env *object.Environment) OverflowMode {
+	if mode, ok := env.Pragma(overflowPragma); ok {
+		return mode.(OverflowMode)
+	}
+
+	return DefaultOverflowMode
+}
+
+/*
+	带溢出检查的整数运算, 只处理 + - * ** <<
+	第二个返回值表示是否溢出
+*/
+func checkedIntegerOperation(operator string, left, right int64) (int64, bool) {
+	switch operator {
+	case "+":
+		result := left + right
+		return result, (left > 0 && right > 0 && result < 0) || (left < 0 && right < 0 && result >= 0)
+	case "-":
+		result := left - right
+		return result, (left >= 0 && right < 0 && result < 0) || (left < 0 && right > 0 && result >= 0)
+	case "*":
+		return checkedMultiply(left, right)
+	case "**":
+		return checkedPow(left, right)
+	case "<<":
+		// 移出的位必须都是符号位, 如 -1 << 63 的结果正好是最小的int64
+		result := left << right
+		return result, left != 0 && (right >= 64 || result>>right != left)
+	default:
+		return 0, false
+	}
+}
+
+/*
+	带溢出检查的快速幂, 指数为非负数
+*/
+func checkedPow(base, exp int64) (int64, bool) {
+	result := int64(1)
+
+	for exp > 0 {
+		var overflowed bool
+		if exp&1 == 1 {
+			if result, overflowed = checkedMultiply(result, base); overflowed {
+				return result, true
+			}
+		}
+		exp >>= 1
+		// 只要还有剩余的指数位, 底数的平方就会乘进结果, 因此平方溢出即结果溢出
+		if exp > 0 {
+			if base, overflowed = checkedMultiply(base, base); overflowed {
+				return result, true
+			}
+		}
+	}
+
+	return result, false
+}
+
+func checkedMultiply(left, right int64) (int64, bool) {
+	if left == 0 || right == 0 {
+		return 0, false
+	}
+
+	result := left * right
+	overflowed := result/right != left || (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64)
+
+	return result, overflowed
+}
+
+/*
+	用BigInt重新计算溢出的整数运算
+*/
+func promotedIntegerOperation(operator string, left, right int64) object.Object {
+	return evalBigIntInfixExpression(operator, &object.BigInt{Value: big.NewInt(left)}, &object.BigInt{Value: big.NewInt(right)})
+}
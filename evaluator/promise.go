@@ -0,0 +1,479 @@
+package evaluator
+
+/*
+	Promise与async函数
+	Promise的回调总是在微任务中执行, 即使登记回调时Promise已经敲定
+	async函数与生成器一样在单独的goroutine中执行, await暂停函数体, 等待的Promise敲定后在微任务中恢复
+	async函数返回Promise: 函数体返回时兑现, 抛出错误时拒绝
+	拒绝的原因与catch捕获的值相同: 抛出的值原样保存, 运行时错误转换为错误对象
+*/
+
+import (
+	"finger/ast"
+	"finger/object"
+	"finger/token"
+)
+
+// 全局的Promise对象, new Promise(executor) 创建Promise
+var promiseConstructor *object.Hash
+
+// Promise的静态方法会调用用户函数, 在init中注册以避免初始化循环
+func init() {
+	promiseConstructor = object.NewHash()
+	statics := map[string]object.BuiltinFunction{
+		"resolve": func(args ...object.Object) object.Object {
+			return promiseResolve(firstArg(args))
+		},
+		"reject": func(args ...object.Object) object.Object {
+			promise := &object.Promise{}
+			rejectPromise(promise, firstArg(args))
+			return promise
+		},
+		"all": func(args ...object.Object) object.Object {
+			return promiseCombinator(args, combineAll)
+		},
+		"allSettled": func(args ...object.Object) object.Object {
+			return promiseCombinator(args, combineAllSettled)
+		},
+		"race": func(args ...object.Object) object.Object {
+			return promiseCombinator(args, combineRace)
+		},
+		"any": func(args ...object.Object) object.Object {
+			return promiseCombinator(args, combineAny)
+		},
+	}
+	for _, name := range []string{"resolve", "reject", "all", "allSettled", "race", "any"} {
+		promiseConstructor.Set(&object.String{Value: name}, &object.Builtin{Fn: statics[name]})
+	}
+	promiseConstructor.Frozen = true
+	globals["Promise"] = promiseConstructor
+}
+
+func firstArg(args []object.Object) object.Object {
+	if len(args) == 0 {
+		return UNDEFINED
+	}
+	return args[0]
+}
+
+/*
+	new Promise(executor): 立即调用executor(resolve, reject), executor抛出的错误拒绝这个Promise
+*/
+func newPromiseFromExecutor(args []object.Object, callSite token.Position) object.Object {
+	executor := firstArg(args)
+	if !isCallable(executor) {
+		return newTypeError("Promise executor must be a function, got %s", executor.Type())
+	}
+
+	promise := &object.Promise{}
+	resolve, reject := resolvingFunctions(promise)
+	if result := applyFunction(executor, []object.Object{resolve, reject}, callSite); isError(result) {
+		reject.Fn(errorValue(result.(*object.Error)))
+	}
+
+	return promise
+}
+
+/*
+	传给executor和then方法的resolve、reject函数, 两者合计只有第一次调用有效
+*/
+func resolvingFunctions(promise *object.Promise) (*object.Builtin, *object.Builtin) {
+	resolved := false
+
+	resolve := &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if !resolved {
+			resolved = true
+			resolvePromise(promise, firstArg(args))
+		}
+		return UNDEFINED
+	}}
+	reject := &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if !resolved {
+			resolved = true
+			rejectPromise(promise, firstArg(args))
+		}
+		return UNDEFINED
+	}}
+
+	return resolve, reject
+}
+
+/*
+	把值转换为Promise, Promise原样返回
+*/
+func promiseResolve(value object.Object) *object.Promise {
+	if promise, ok := value.(*object.Promise); ok {
+		return promise
+	}
+
+	promise := &object.Promise{}
+	resolvePromise(promise, value)
+	return promise
+}
+
+/*
+	用value兑现Promise, value是Promise或者有then方法的对象时跟随它的结果
+*/
+func resolvePromise(promise *object.Promise, value object.Object) {
+	if promise.State != object.PENDING {
+		return
+	}
+
+	switch v := value.(type) {
+	case *object.Promise:
+		if v == promise {
+			rejectPromise(promise, newErrorObject("promise cannot be resolved with itself", object.TYPE_ERROR))
+			return
+		}
+		subscribe(v, func(result object.Object) {
+			resolvePromise(promise, result)
+		}, func(reason object.Object) {
+			rejectPromise(promise, reason)
+		})
+		return
+	case *object.Hash, *object.Instance:
+		if then := getProperty(v, "then"); isCallable(then) {
+			enqueueMicrotask(func() {
+				resolve, reject := resolvingFunctions(promise)
+				if result := applyFunction(then, []object.Object{resolve, reject}, token.Position{}); isError(result) {
+					reject.Fn(errorValue(result.(*object.Error)))
+				}
+			})
+			return
+		}
+	}
+
+	settlePromise(promise, object.FULFILLED, value)
+}
+
+func rejectPromise(promise *object.Promise, reason object.Object) {
+	settlePromise(promise, object.REJECTED, reason)
+}
+
+/*
+	敲定Promise并把等待的回调放入微任务队列, 已经敲定的Promise保持不变
+*/
+func settlePromise(promise *object.Promise, state object.PromiseState, value object.Object) {
+	if promise.State != object.PENDING {
+		return
+	}
+
+	promise.State = state
+	promise.Value = value
+	for _, reaction := range promise.Reactions {
+		enqueueMicrotask(reaction)
+	}
+	promise.Reactions = nil
+
+	if state == object.REJECTED && !promise.Handled {
+		trackRejection(promise)
+	}
+}
+
+/*
+	登记Promise敲定后在微任务中执行的回调
+*/
+func subscribe(promise *object.Promise, onFulfilled, onRejected func(object.Object)) {
+	promise.Handled = true
+
+	reaction := func() {
+		if promise.State == object.FULFILLED {
+			onFulfilled(promise.Value)
+		} else {
+			onRejected(promise.Value)
+		}
+	}
+
+	if promise.State == object.PENDING {
+		promise.Reactions = append(promise.Reactions, reaction)
+	} else {
+		enqueueMicrotask(reaction)
+	}
+}
+
+/*
+	Promise的then、catch和finally方法, 都返回一个新的Promise
+*/
+func promiseMethod(promise *object.Promise, name string) object.Object {
+	switch name {
+	case "then":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			var onRejected object.Object = UNDEFINED
+			if len(args) > 1 {
+				onRejected = args[1]
+			}
+			return promiseThen(promise, firstArg(args), onRejected)
+		}}
+	case "catch":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return promiseThen(promise, UNDEFINED, firstArg(args))
+		}}
+	case "finally":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return promiseFinally(promise, firstArg(args))
+		}}
+	default:
+		return UNDEFINED
+	}
+}
+
+/*
+	then: 用处理函数的返回值兑现新的Promise, 处理函数抛出错误时拒绝它
+	不是函数的处理函数把结果原样传给新的Promise
+*/
+func promiseThen(promise *object.Promise, onFulfilled, onRejected object.Object) *object.Promise {
+	derived := &object.Promise{}
+
+	handle := func(handler, value object.Object, rejected bool) {
+		if !isCallable(handler) {
+			if rejected {
+				rejectPromise(derived, value)
+			} else {
+				resolvePromise(derived, value)
+			}
+			return
+		}
+		result := applyFunction(handler, []object.Object{value}, token.Position{})
+		if err, ok := result.(*object.Error); ok {
+			rejectPromise(derived, errorValue(err))
+			return
+		}
+		resolvePromise(derived, result)
+	}
+
+	subscribe(promise, func(value object.Object) {
+		handle(onFulfilled, value, false)
+	}, func(reason object.Object) {
+		handle(onRejected, reason, true)
+	})
+
+	return derived
+}
+
+/*
+	finally: 无论结果如何都调用fn, 然后把原来的结果传给新的Promise
+	fn返回Promise时等待它敲定, fn抛出错误或返回的Promise被拒绝时新的Promise以此拒绝
+*/
+func promiseFinally(promise *object.Promise, fn object.Object) *object.Promise {
+	derived := &object.Promise{}
+
+	handle := func(value object.Object, rejected bool) {
+		passThrough := func(object.Object) {
+			if rejected {
+				rejectPromise(derived, value)
+			} else {
+				resolvePromise(derived, value)
+			}
+		}
+		if !isCallable(fn) {
+			passThrough(nil)
+			return
+		}
+		result := applyFunction(fn, []object.Object{}, token.Position{})
+		if err, ok := result.(*object.Error); ok {
+			rejectPromise(derived, errorValue(err))
+			return
+		}
+		subscribe(promiseResolve(result), passThrough, func(reason object.Object) {
+			rejectPromise(derived, reason)
+		})
+	}
+
+	subscribe(promise, func(value object.Object) {
+		handle(value, false)
+	}, func(reason object.Object) {
+		handle(reason, true)
+	})
+
+	return derived
+}
+
+/*
+	Promise.all、allSettled、race和any的公共部分: 把可迭代对象中的每个值转换为Promise后交给combine
+	参数不可迭代时返回被拒绝的Promise
+*/
+func promiseCombinator(args []object.Object, combine func(*object.Promise, []*object.Promise)) object.Object {
+	derived := &object.Promise{}
+
+	iter, err := getIterator(firstArg(args))
+	if err != nil {
+		rejectPromise(derived, errorValue(err))
+		return derived
+	}
+
+	promises := []*object.Promise{}
+	for {
+		value, done := iter.Next()
+		if isError(value) {
+			rejectPromise(derived, errorValue(value.(*object.Error)))
+			return derived
+		}
+		if done {
+			break
+		}
+		promises = append(promises, promiseResolve(value))
+	}
+
+	combine(derived, promises)
+	return derived
+}
+
+/*
+	全部兑现时以结果数组兑现, 任何一个被拒绝时以它的原因拒绝
+*/
+func combineAll(derived *object.Promise, promises []*object.Promise) {
+	results := make([]object.Object, len(promises))
+	remaining := len(promises)
+	if remaining == 0 {
+		resolvePromise(derived, &object.Array{Elements: results})
+		return
+	}
+
+	for i, promise := range promises {
+		i := i
+		subscribe(promise, func(value object.Object) {
+			results[i] = value
+			remaining--
+			if remaining == 0 {
+				resolvePromise(derived, &object.Array{Elements: results})
+			}
+		}, func(reason object.Object) {
+			rejectPromise(derived, reason)
+		})
+	}
+}
+
+/*
+	全部敲定后兑现, 每个结果是 {status: "fulfilled", value} 或 {status: "rejected", reason}
+*/
+func combineAllSettled(derived *object.Promise, promises []*object.Promise) {
+	results := make([]object.Object, len(promises))
+	remaining := len(promises)
+	if remaining == 0 {
+		resolvePromise(derived, &object.Array{Elements: results})
+		return
+	}
+
+	settled := func(i int, status, key string, value object.Object) {
+		outcome := object.NewHash()
+		outcome.Set(&object.String{Value: "status"}, &object.String{Value: status})
+		outcome.Set(&object.String{Value: key}, value)
+		results[i] = outcome
+		remaining--
+		if remaining == 0 {
+			resolvePromise(derived, &object.Array{Elements: results})
+		}
+	}
+
+	for i, promise := range promises {
+		i := i
+		subscribe(promise, func(value object.Object) {
+			settled(i, "fulfilled", "value", value)
+		}, func(reason object.Object) {
+			settled(i, "rejected", "reason", reason)
+		})
+	}
+}
+
+/*
+	跟随第一个敲定的Promise, 没有Promise时永远等待
+*/
+func combineRace(derived *object.Promise, promises []*object.Promise) {
+	for _, promise := range promises {
+		subscribe(promise, func(value object.Object) {
+			resolvePromise(derived, value)
+		}, func(reason object.Object) {
+			rejectPromise(derived, reason)
+		})
+	}
+}
+
+/*
+	以第一个兑现的值兑现, 全部被拒绝时以AggregateError拒绝, 其中的errors是各个原因
+*/
+func combineAny(derived *object.Promise, promises []*object.Promise) {
+	reasons := make([]object.Object, len(promises))
+	remaining := len(promises)
+
+	reject := func() {
+		err := newErrorObject("all promises were rejected", "AggregateError")
+		err.Set(&object.String{Value: "errors"}, &object.Array{Elements: reasons})
+		rejectPromise(derived, err)
+	}
+	if remaining == 0 {
+		reject()
+		return
+	}
+
+	for i, promise := range promises {
+		i := i
+		subscribe(promise, func(value object.Object) {
+			resolvePromise(derived, value)
+		}, func(reason object.Object) {
+			reasons[i] = reason
+			remaining--
+			if remaining == 0 {
+				reject()
+			}
+		})
+	}
+}
+
+/*
+	调用async函数: 同步执行到第一个await, 返回代表函数结果的Promise
+*/
+func callAsyncFunction(fn *object.Function, env *object.Environment, name string, callSite token.Position) object.Object {
+	promise := &object.Promise{}
+	// 暂停时只有等待的Promise的回调引用handle, 这个Promise永远不会敲定并被回收时函数体随之结束
+	handle := newGeneratorHandle(newGeneratorRun(fn, env, name, callSite, "await"))
+
+	var step func(signal generatorSignal)
+	step = func(signal generatorSignal) {
+		value, done := handle.resume(signal)
+		if done {
+			if err, ok := value.(*object.Error); ok {
+				rejectPromise(promise, errorValue(err))
+			} else {
+				resolvePromise(promise, value)
+			}
+			return
+		}
+
+		// 暂停在await上, 等待的值敲定后恢复函数体
+		subscribe(promiseResolve(value), func(result object.Object) {
+			step(generatorSignal{value: result})
+		}, func(reason object.Object) {
+			step(generatorSignal{value: reason, throw: true})
+		})
+	}
+	step(generatorSignal{value: UNDEFINED})
+
+	return promise
+}
+
+/*
+	await表达式求值
+	在async函数中暂停函数体; 在顶层代码中原地运行事件循环, 直到等待的Promise敲定
+	等待被拒绝的Promise时抛出拒绝的原因
+*/
+func evalAwaitExpression(node *ast.AwaitExpression, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	if hook, ok := env.Get("await"); ok {
+		return withPosition(hook.(*generatorRun).yield(value, node.Token.Pos), node.Token)
+	}
+
+	promise := promiseResolve(value)
+	promise.Handled = true
+	if err := runUntilSettled(promise); err != nil {
+		return withPosition(err, node.Token)
+	}
+
+	if promise.State == object.REJECTED {
+		return thrownError(promise.Value, node.Token.Pos)
+	}
+	return promise.Value
+}
@@ -0,0 +1,57 @@
+package evaluator
+
+/*
+	基于原型的对象
+	哈希表可以通过 __proto__ 链接到另一个哈希表, 读取自身没有的属性时沿原型链查找
+	原型中的函数通过 obj.method() 调用时, this 是 obj 而不是原型
+*/
+
+import (
+	"finger/object"
+)
+
+/*
+	返回哈希表的原型, 没有原型时为null
+*/
+func protoOf(hash *object.Hash) object.Object {
+	if hash.Proto == nil {
+		return NULL
+	}
+	return hash.Proto
+}
+
+/*
+	设置哈希表的原型, 原型只能是哈希表或null, 并且不能形成环
+*/
+func setPrototype(hash *object.Hash, proto object.Object) *object.Error {
+	switch proto := proto.(type) {
+	case *object.Hash:
+		if proto == hash || proto.InheritsFrom(hash) {
+			return newTypeError("cyclic __proto__ value")
+		}
+		hash.Proto = proto
+	case *object.Null:
+		hash.Proto = nil
+	default:
+		return newTypeError("object prototype may only be a HASH or null, got %s", proto.Type())
+	}
+
+	return nil
+}
+
+/*
+	instanceof运算符
+	右边是类时检查左边是否是该类或其子类的实例, 右边是哈希表时检查它是否在左边的原型链上
+*/
+func evalInstanceof(left, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Class:
+		instance, ok := left.(*object.Instance)
+		return nativeBoolToBooleanObject(ok && instance.Class.IsSubclassOf(right))
+	case *object.Hash:
+		hash, ok := left.(*object.Hash)
+		return nativeBoolToBooleanObject(ok && hash.InheritsFrom(right))
+	default:
+		return newTypeError("right-hand side of 'instanceof' is not a class or hash: %s", right.Type())
+	}
+}
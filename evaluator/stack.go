@@ -0,0 +1,46 @@
+package evaluator
+
+/*
+	调用栈
+	applyFunction每调用一个函数压入一帧, 创建错误时复制当前的调用栈
+*/
+
+import (
+	"finger/object"
+	"finger/token"
+)
+
+// 当前的调用栈, 最外层的调用在前
+var callStack []object.StackFrame
+
+func pushFrame(name string, callSite token.Position) {
+	callStack = append(callStack, object.StackFrame{Function: name, Pos: callSite})
+}
+
+func popFrame() {
+	callStack = callStack[:len(callStack)-1]
+}
+
+/*
+	复制当前的调用栈
+*/
+func captureStack() []object.StackFrame {
+	if len(callStack) == 0 {
+		return nil
+	}
+	return append([]object.StackFrame(nil), callStack...)
+}
+
+/*
+	调用栈中显示的函数名, 方法显示为 Class.method
+*/
+func functionName(fn *object.Function, home *object.Class) string {
+	name := fn.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	if home != nil && home.Name != "" {
+		return home.Name + "." + name
+	}
+	return name
+}
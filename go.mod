@@ -2,5 +2,4 @@ module finger
 
 go 1.21.3
 
-require (
-)
\ No newline at end of file
+require golang.org/x/text v0.21.0
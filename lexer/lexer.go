@@ -2,13 +2,23 @@ package lexer
 
 import (
 	"finger/token"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
 	input        string
 	position     int  // 所输入字符串中的当前位置(指向当前字符)
 	readPosition int  // 当前读取的下一个位置(指向当前字符的下一个字符)
-	ch           byte // 当前正在查看的字符
+	ch           rune // 当前正在查看的字符(按UTF-8解码)
+	line         int  // 当前字符所在的行号
+	column       int  // 当前字符所在的列号
+	start        token.Position // 当前词法单元的起始位置
+	errors       []*Error // 词法错误, 每个ILLEGAL词法单元和模板字符串中的每个非法转义各对应一条
+	templates    []int // 每层未结束的模板插值中尚未闭合的左花括号数
+	lastLine     int // 上一个词法单元结束时所在的行号, 不计注释
 }
 
 /*
@@ -16,7 +26,7 @@ type Lexer struct {
 */
 func New(input string) *Lexer {
 	// 创建一个词法分析器
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1, lastLine: 1}
 	// 读取下一个字符
 	l.readChar()
 	return l
@@ -24,51 +34,121 @@ func New(input string) *Lexer {
 
 /*
 	读取下一个字符，并前移其在input中的位置
-	ch = 0 意味着NIL字符,EOF, 只支持ASCII字符
+	ch = 0 意味着NIL字符,EOF; 非法的UTF-8字节解码为utf8.RuneError
 */
 func (l *Lexer) readChar() {
+	// 越过换行符时更新行号和列号
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
 	// 如果读取位置超过了输入字符串的长度，则将字符设置为0(表示EOF)
+	width := 0
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
 		// 否则，将当前字符设置为输入字符串中readPosition位置的字符
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	// 更新读取位置
 	l.position = l.readPosition
-	l.readPosition++
+	l.readPosition += width
+	if width == 0 {
+		// EOF之后仍然前移, 使position停在输入末尾之后
+		l.readPosition++
+	}
+	l.column++
+}
+
+/*
+	返回当前字符在源码中的位置
+*/
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{Offset: l.position, Line: l.line, Column: l.column}
+}
+
+/*
+	词法错误
+*/
+type Error struct {
+	Pos     token.Position // 出错的位置
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message + " at " + e.Pos.String()
+}
+
+/*
+	返回词法错误
+*/
+func (l *Lexer) Errors() []*Error {
+	return l.errors
+}
+
+/*
+	记录一条带位置的词法错误
+*/
+func (l *Lexer) addError(pos token.Position, format string, args ...interface{}) {
+	l.errors = append(l.errors, &Error{Pos: pos, Message: fmt.Sprintf(format, args...)})
 }
 
 /*
 	窥视输入中的下一个字符，不会移动输入中的指针位置
 */
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	// 如果读取位置超过了输入字符串的长度，则将字符设置为0(表示EOF)
 	if l.readPosition >= len(l.input) {
 		return 0
 	}
 	// 否则，返回输入字符串中readPosition位置的字符
-	return l.input[l.readPosition]
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
 }
 
 /*
 	窥视第二个字符之后的字符
 */
-func (l *Lexer) peekNextChar() byte {
-	if l.readPosition + 1 >= len(l.input) {
+func (l *Lexer) peekNextChar() rune {
+	if l.readPosition >= len(l.input) {
 		return 0
 	}
-	return l.input[l.readPosition + 1]
+	_, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	if l.readPosition + width >= len(l.input) {
+		return 0
+	}
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition + width:])
+	return ch
 }
 
 /*
-	检查当前正在查看的字符，根据字符返回相应的词法单元。
+	返回下一个词法单元, 并记录它在源码中的起始位置
+	以及它与上一个词法单元之间是否隔着换行(包括跨行的注释)
 */
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespace()
 
+	l.start = l.currentPosition()
+	tok := l.readToken()
+	tok.Pos = l.start
+	tok.End = l.currentPosition()
+	// 跳过注释时readToken会递归调用NextToken, 此时换行已经由内层调用算好
+	tok.NewlineBefore = tok.NewlineBefore || tok.Pos.Line > l.lastLine
+
+	// 文档注释不是语句的一部分, 换行按它前面的词法单元计算
+	if tok.Type != token.DOC_COMMENT {
+		l.lastLine = l.line
+	}
+
+	return tok
+}
+
+/*
+	检查当前正在查看的字符，根据字符返回相应的词法单元。
+*/
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
+
 	switch l.ch {
 	/* 运算符的处理 */
 	// = | == | ===
@@ -80,16 +160,16 @@ func (l *Lexer) NextToken() token.Token {
 				l.readChar()
 				l.readChar()
 				literal := string(ch) + "=="
-				tok = token.Token{Type: token.EQ, Literal: literal}
+				tok = token.Token{Type: token.STRICT_EQ, Literal: literal}
 			} else {
-			  // 处理 == 
+				// 处理 宽松相等
 				ch := l.ch
 				l.readChar()
 				literal := string(ch) + string(l.ch)
 				tok = token.Token{Type: token.EQ, Literal: literal}
 			}
 		} else {
-			// 处理 模糊相等
+			// 处理 赋值
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	// ! | != | !==
@@ -101,8 +181,8 @@ func (l *Lexer) NextToken() token.Token {
 				// 读取后面的两个字符
 				l.readChar()
 				l.readChar()
-				literal := string(ch) + "!=" // 即 !==
-				tok = token.Token{Type: token.NOT_EQ, Literal: literal}
+				literal := string(ch) + "==" // 即 !==
+				tok = token.Token{Type: token.STRICT_NOT_EQ, Literal: literal}
 			} else {
 				// 处理 != 
 				ch := l.ch
@@ -157,7 +237,7 @@ func (l *Lexer) NextToken() token.Token {
 			// 否则，返回-
 			tok = newToken(token.MINUS, l.ch)
 		}
-	// 处理 * | *=
+	// 处理 * | *= | **
 	case '*':
 		if l.peekChar() == '=' {
 			// 处理 *=
@@ -165,18 +245,24 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			literal := string(ch) + "="
 			tok = token.Token{Type: token.ASTERISK_EQ, Literal: literal}
+		} else if l.peekChar() == '*' {
+			// 处理 **
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + "*"
+			tok = token.Token{Type: token.POWER, Literal: literal}
 		} else {
 			// 否则，返回*
 			tok = newToken(token.ASTERISK, l.ch)
 		}
-	// 处理 / | /= | //
+	// 处理 / | /= | 单行注释 | 块注释 | 文档注释
 	case '/':
 		if l.peekChar() == '/' {
-			// 处理 //
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + "/"
-			tok = token.Token{Type: token.COMMENT, Literal: literal}
+			// 单行注释, 跳过后返回下一个词法单元
+			l.skipLineComment()
+			return l.NextToken()
+		} else if l.peekChar() == '*' {
+			return l.readBlockComment()
 		} else if l.peekChar() == '=' {
 			// 处理 /=
 			ch := l.ch
@@ -312,32 +398,50 @@ func (l *Lexer) NextToken() token.Token {
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
 	case '{':
+		if n := len(l.templates); n > 0 {
+			l.templates[n-1]++
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
+		if n := len(l.templates); n > 0 {
+			if l.templates[n-1] == 0 {
+				// 插值结束, 继续读取模板字符串的下一段
+				l.templates = l.templates[:n-1]
+				return l.readTemplate(false)
+			}
+			l.templates[n-1]--
+		}
 		tok = newToken(token.RBRACE, l.ch)
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	// 处理字符串
-	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
-		return tok
+	case '"', '\'':
+		return l.readString()
+	// 处理模板字符串
+	case '`':
+		return l.readTemplate(true)
 	default:
-		if isLetter(l.ch) {
+		if l.ch == 'r' && (l.peekChar() == '"' || l.peekChar() == '\'') {
+			// 原始字符串 r"..."
+			return l.readRawString()
+		} else if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.NUMBER
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
+			if tok.Type == token.ILLEGAL {
+				l.addError(l.start, "invalid number literal %s", tok.Literal)
+			}
 			return tok
 		} else if l.ch == 0 {
 			tok.Type = token.EOF
 			tok.Literal = ""
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
+			l.addError(l.start, "unexpected character %q", l.ch)
 		}
 	}
 
@@ -355,52 +459,414 @@ func (l *Lexer) skipWhitespace() {
 }
 
 /*
-	读入一个数字, 只能读取整数目前，忽略了浮点数、十六进制数、八进制数
-	@todo: 需要扩展
+	跳过单行注释, 停在换行符上
 */
-func (l *Lexer) readNumber() string {
+func (l *Lexer) skipLineComment() {
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+/*
+	读入一个块注释, 块注释可以嵌套, 内层的注释需要各自闭合
+	普通块注释直接跳过, 返回其后的下一个词法单元
+	以两个星号开头的文档注释返回token.DOC_COMMENT, 字面量为整理后的正文
+	未闭合的块注释返回token.ILLEGAL并记录错误
+*/
+func (l *Lexer) readBlockComment() token.Token {
+	start := l.start
+	isDoc := strings.HasPrefix(l.input[l.position:], "/**") && !strings.HasPrefix(l.input[l.position:], "/**/")
+
+	// 跳过开头的 /*
+	l.readChar()
+	l.readChar()
+	bodyStart := l.position
+	depth := 1
+	for depth > 0 {
+		switch {
+		case l.ch == 0:
+			l.addError(start, "unterminated block comment")
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[start.Offset:l.position]}
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+		}
+		l.readChar()
+	}
+
+	if !isDoc {
+		return l.NextToken()
+	}
+
+	// 去掉结尾的注释结束符
+	body := l.input[bodyStart : l.position-2]
+	return token.Token{Type: token.DOC_COMMENT, Literal: cleanDocComment(body)}
+}
+
+/*
+	整理文档注释的正文: 去掉每行开头的空白和 *, 以及首尾的空行
+*/
+func cleanDocComment(body string) string {
+	lines := strings.Split(strings.TrimPrefix(body, "*"), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "*") {
+			line = strings.TrimSpace(line[1:])
+		}
+		lines[i] = line
+	}
+
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+/*
+	读入一个数字, 返回词法单元类型和字面量
+	整数: 123 | 1_000_000 | 0xFF | 0o17 | 0b1010 -> token.NUMBER
+	浮点数: 3.14 | 1e-9 | 2.5E+3 -> token.FLOAT
+	大整数: 123n | 0xFFn -> token.BIGINT
+	下划线只能出现在两个数字之间, 否则返回token.ILLEGAL
+*/
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position
-	for isDigit(l.ch) {
+	var tokType token.TokenType = token.NUMBER
+	valid := true
+
+	if l.ch == '0' && radixDigit(l.peekChar()) != nil {
+		// 处理 0x | 0o | 0b
+		isRadixDigit := radixDigit(l.peekChar())
+		l.readChar()
 		l.readChar()
+		valid = l.readDigits(isRadixDigit)
+	} else {
+		valid = l.readDigits(isDigit)
+
+		// 小数部分, 要求小数点后紧跟数字, 以免吞掉 1.. 之类的写法
+		if l.ch == '.' && isDigit(l.peekChar()) {
+			tokType = token.FLOAT
+			l.readChar()
+			valid = l.readDigits(isDigit) && valid
+		}
+
+		// 指数部分
+		if l.ch == 'e' || l.ch == 'E' {
+			next := l.peekChar()
+			if isDigit(next) || ((next == '+' || next == '-') && isDigit(l.peekNextChar())) {
+				tokType = token.FLOAT
+				l.readChar()
+				if l.ch == '+' || l.ch == '-' {
+					l.readChar()
+				}
+				valid = l.readDigits(isDigit) && valid
+			}
+		}
 	}
-	return l.input[position:l.position]
+
+	// 整数后紧跟 n 表示大整数
+	if tokType == token.NUMBER && l.ch == 'n' {
+		tokType = token.BIGINT
+		l.readChar()
+	}
+
+	if !valid {
+		return token.ILLEGAL, l.input[position:l.position]
+	}
+
+	return tokType, l.input[position:l.position]
+}
+
+/*
+	读入一串数字, 允许在数字之间使用下划线分隔
+	返回数字是否合法: 至少有一个数字, 且下划线不在开头、结尾或连续出现
+*/
+func (l *Lexer) readDigits(isValidDigit func(rune) bool) bool {
+	valid := isValidDigit(l.ch)
+	prevUnderscore := false
+
+	for isValidDigit(l.ch) || l.ch == '_' {
+		if l.ch == '_' {
+			if prevUnderscore || !valid {
+				valid = false
+			}
+			prevUnderscore = true
+		} else {
+			prevUnderscore = false
+		}
+		l.readChar()
+	}
+
+	return valid && !prevUnderscore
+}
+
+/*
+	根据进制前缀(x | o | b)返回对应的数字检查函数, 不是进制前缀时返回nil
+*/
+func radixDigit(prefix rune) func(rune) bool {
+	switch prefix {
+	case 'x', 'X':
+		return isHexDigit
+	case 'o', 'O':
+		return func(ch rune) bool { return '0' <= ch && ch <= '7' }
+	case 'b', 'B':
+		return func(ch rune) bool { return ch == '0' || ch == '1' }
+	default:
+		return nil
+	}
+}
+
+/*
+	检查字符是否是十六进制数字
+*/
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
 }
 
 /*
 	检查字符是否是数字
 */
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
 /*
 	检查字符是否是字母, finger处理器可处理的语言格式
+	支持Unicode字母, 因此标识符可以使用中文等非ASCII字符
 */
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+	}
+	return unicode.IsLetter(ch)
+}
+
+/*
+	检查字符能否出现在标识符中(首字符之外)
+*/
+func isIdentifierChar(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return isLetter(ch) || isDigit(ch)
+	}
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || unicode.Is(unicode.Mn, ch) || unicode.Is(unicode.Mc, ch)
 }
 
 /*
 	创建一个token
 */
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
 /*
-	读入一个字符串, 并返回字符串
+	读入一个单引号或双引号字符串, 并处理转义序列
+	字符串不能跨行, 未闭合或含非法转义时返回token.ILLEGAL并记录错误
+*/
+func (l *Lexer) readString() token.Token {
+	quote := l.ch
+	var out strings.Builder
+	valid := true
+
+	for {
+		l.readChar()
+		switch {
+		case l.ch == quote:
+			l.readChar()
+			if !valid {
+				return token.Token{Type: token.ILLEGAL, Literal: l.input[l.start.Offset:l.position]}
+			}
+			return token.Token{Type: token.STRING, Literal: out.String()}
+		case l.ch == 0 || l.ch == '\n':
+			// 换行符不属于字符串, 留给下一个词法单元
+			l.addError(l.start, "unterminated string literal")
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[l.start.Offset:l.position]}
+		case l.ch == '\\' && (l.peekChar() == 0 || l.peekChar() == '\n'):
+			// 反斜杠之后没有可以转义的字符, 字符串在行尾或输入末尾未闭合
+			l.readChar()
+			l.addError(l.start, "unterminated string literal")
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[l.start.Offset:l.position]}
+		case l.ch == '\\':
+			if !l.readEscapeSequence(&out) {
+				valid = false
+			}
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+/*
+	读入从当前的反斜杠开始的转义序列, 把它表示的字符写入out
+	转义序列非法时记录错误并返回false; 错误中的转义序列文本截止到输入末尾为止
+*/
+func (l *Lexer) readEscapeSequence(out *strings.Builder) bool {
+	pos := l.currentPosition()
+	l.readChar()
+	ch, ok := l.readEscape()
+	if !ok {
+		end := l.readPosition
+		if end > len(l.input) {
+			end = len(l.input)
+		}
+		l.addError(pos, "invalid escape sequence %s", l.input[pos.Offset:end])
+	}
+	out.WriteRune(ch)
+
+	return ok
+}
+
+/*
+	读入反斜杠之后的转义序列, 返回其表示的字符
+	支持 \n \t \r \b \f \v \0 \\ \" \' \` \xHH \uHHHH \u{H...}
+	读取结束时l.ch停在转义序列的最后一个字符上
+*/
+func (l *Lexer) readEscape() (rune, bool) {
+	switch l.ch {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case 'r':
+		return '\r', true
+	case 'b':
+		return '\b', true
+	case 'f':
+		return '\f', true
+	case 'v':
+		return '\v', true
+	case '0':
+		return 0, !isDigit(l.peekChar())
+	case '\\', '"', '\'', '`':
+		return l.ch, true
+	case 'x':
+		return l.readHexEscape(2)
+	case 'u':
+		if l.peekChar() != '{' {
+			return l.readHexEscape(4)
+		}
+		// \u{...} 形式, 1到6位十六进制数
+		l.readChar()
+		var value rune
+		digits := 0
+		for isHexDigit(l.peekChar()) {
+			l.readChar()
+			value = value*16 + hexValue(l.ch)
+			digits++
+			if digits > 6 {
+				return utf8.RuneError, false
+			}
+		}
+		if digits == 0 || l.peekChar() != '}' {
+			return utf8.RuneError, false
+		}
+		l.readChar()
+		if !utf8.ValidRune(value) {
+			return utf8.RuneError, false
+		}
+		return value, true
+	default:
+		return utf8.RuneError, false
+	}
+}
+
+/*
+	读入固定位数的十六进制转义, 如 \xHH 和 \uHHHH
+*/
+func (l *Lexer) readHexEscape(digits int) (rune, bool) {
+	var value rune
+	for i := 0; i < digits; i++ {
+		if !isHexDigit(l.peekChar()) {
+			return utf8.RuneError, false
+		}
+		l.readChar()
+		value = value*16 + hexValue(l.ch)
+	}
+	if !utf8.ValidRune(value) {
+		return utf8.RuneError, false
+	}
+	return value, true
+}
+
+/*
+	返回十六进制数字的值
 */
-func (l *Lexer) readString() string {
+func hexValue(ch rune) rune {
+	switch {
+	case isDigit(ch):
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
+
+/*
+	读入模板字符串的一段, 从 ` 或插值结尾的 } 开始, 到 ${ 或 ` 结束
+	模板字符串可以跨行, 处理与字符串相同的转义序列, 另外 \$ 表示字面的$, 因此 \` 和 \${ 不会结束这一段
+	Literal是处理转义之后的文本, Raw是源码中的原始文本, 供标签函数使用
+	含非法转义时记录错误但仍返回模板词法单元, 以免插值的括号配对被打乱
+	遇到 ${ 时记录一层插值, 之后的词法单元按普通代码读取, 直到与之匹配的 }
+*/
+func (l *Lexer) readTemplate(head bool) token.Token {
 	position := l.position + 1
+	var out strings.Builder
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 { 
+		switch {
+		case l.ch == '`':
+			tok := token.Token{Type: token.TEMPLATE_TAIL, Literal: out.String(), Raw: l.input[position:l.position]}
+			if head {
+				tok.Type = token.TEMPLATE
+			}
+			l.readChar()
+			return tok
+		case l.ch == '$' && l.peekChar() == '{':
+			tok := token.Token{Type: token.TEMPLATE_MIDDLE, Literal: out.String(), Raw: l.input[position:l.position]}
+			if head {
+				tok.Type = token.TEMPLATE_HEAD
+			}
+			l.readChar()
+			l.readChar()
+			l.templates = append(l.templates, 0)
+			return tok
+		case l.ch == 0:
+			l.addError(l.start, "unterminated template literal")
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[l.start.Offset:l.position]}
+		case l.ch == '\\' && l.peekChar() == 0:
+			// 输入在反斜杠之后结束, 下一次循环报告模板未闭合
+		case l.ch == '\\' && l.peekChar() == '$':
+			l.readChar()
+			out.WriteRune('$')
+		case l.ch == '\\':
+			l.readEscapeSequence(&out)
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+/*
+	读入 r"..." 或 r'...' 形式的原始字符串
+	原始字符串可以跨行, 不处理转义序列, 也不做插值, 用于正则表达式、Windows路径等含反斜杠的文本
+*/
+func (l *Lexer) readRawString() token.Token {
+	l.readChar()
+	quote := l.ch
+	position := l.position + 1
+	for {
+		l.readChar()
+		if l.ch == quote {
 			break
 		}
+		if l.ch == 0 {
+			l.addError(l.start, "unterminated raw string literal")
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[l.start.Offset:l.position]}
+		}
 	}
 	str := l.input[position:l.position]
 	l.readChar()
-	return str
+	return token.Token{Type: token.STRING, Literal: str}
 }
 
 /*
@@ -418,7 +884,7 @@ func (l *Lexer) readIdentifier() string {
 	}
 
 	// 普通标识符处理
-	for isLetter(l.ch) || isDigit(l.ch) {
+	for isIdentifierChar(l.ch) {
 		l.readChar()
 	}
 
@@ -429,7 +895,7 @@ func (l *Lexer) readIdentifier() string {
 	读取一个特殊标识符
 */
 func (l *Lexer) readSpecialIdentifier(startPosition int) string {
-	for isLetter(l.ch) || l.ch == '_' || isDigit(l.ch) {
+	for isIdentifierChar(l.ch) {
 		l.readChar()
 	}
 	word := l.input[startPosition:l.position]
@@ -181,7 +181,380 @@ func TestModernFeatures(t *testing.T) {
 	runTokenTest(t, input, tests)
 }
 
+func TestNumberLiterals(t *testing.T) {
+	input := `42 1_000_000 0xFF 0o17 0b1010 3.14 1e-9 2.5E+3 1_0.5 1__0 0x 7.foo 123n 0xFFn 2 ** 3`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.NUMBER, "42"},
+		{token.NUMBER, "1_000_000"},
+		{token.NUMBER, "0xFF"},
+		{token.NUMBER, "0o17"},
+		{token.NUMBER, "0b1010"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E+3"},
+		{token.FLOAT, "1_0.5"},
+		{token.ILLEGAL, "1__0"},
+		{token.ILLEGAL, "0x"},
+		{token.NUMBER, "7"},
+		{token.DOT, "."},
+		{token.IDENT, "foo"},
+		{token.BIGINT, "123n"},
+		{token.BIGINT, "0xFFn"},
+		{token.NUMBER, "2"},
+		{token.POWER, "**"},
+		{token.NUMBER, "3"},
+		{token.EOF, ""},
+	}
+
+	runTokenTest(t, input, tests)
+}
+
+func TestEqualityOperators(t *testing.T) {
+	input := `a == b; a === b; a != b; a !== b;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.EQ, "=="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.STRICT_EQ, "==="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.NOT_EQ, "!="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.STRICT_NOT_EQ, "!=="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+	}
+
+	runTokenTest(t, input, tests)
+}
+
+func TestUnicodeInput(t *testing.T) {
+	input := `let 名字 = "你好, 世界"; let café_2 = 名字;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "名字"},
+		{token.ASSIGN, "="},
+		{token.STRING, "你好, 世界"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENT, "café_2"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "名字"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	runTokenTest(t, input, tests)
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let 变量 = 1;\n  变量 + 2"
+
+	tests := []struct {
+		literal string
+		line    int
+		column  int
+	}{
+		{"let", 1, 1},
+		{"变量", 1, 5},
+		{"=", 1, 8},
+		{"1", 1, 10},
+		{";", 1, 11},
+		{"变量", 2, 3},
+		{"+", 2, 6},
+		{"2", 2, 8},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.literal || tok.Pos.Line != tt.line || tok.Pos.Column != tt.column {
+			t.Fatalf("tests[%d] - expected %q at %d:%d, got %q at %s",
+				i, tt.literal, tt.line, tt.column, tok.Literal, tok.Pos)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	input := `"a\"b" 'it\'s' "tab\there\n" "\\" "\u4f60\u{597D}\x41" 'say "hi"' ` + "r\"raw\\n\nline\"" + ` "bad\q" "\u{110000}"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING, `a"b`},
+		{token.STRING, "it's"},
+		{token.STRING, "tab\there\n"},
+		{token.STRING, `\`},
+		{token.STRING, "你好A"},
+		{token.STRING, `say "hi"`},
+		{token.STRING, "raw\\n\nline"},
+		{token.ILLEGAL, `"bad\q"`},
+		{token.ILLEGAL, `"\u{110000}"`},
+		{token.EOF, ""},
+	}
+
+	runTokenTest(t, input, tests)
+}
+
+func TestUnterminatedLiterals(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedTypes []token.TokenType
+		expectedError string
+	}{
+		{"\"abc", []token.TokenType{token.ILLEGAL, token.EOF}, "unterminated string literal at 1:1"},
+		{"x = 'abc\ny", []token.TokenType{token.IDENT, token.ASSIGN, token.ILLEGAL, token.IDENT}, "unterminated string literal at 1:5"},
+		{"r'abc\n", []token.TokenType{token.ILLEGAL, token.EOF}, "unterminated raw string literal at 1:1"},
+		{"`abc\n", []token.TokenType{token.ILLEGAL, token.EOF}, "unterminated template literal at 1:1"},
+		{"`abc\\", []token.TokenType{token.ILLEGAL, token.EOF}, "unterminated template literal at 1:1"},
+		{"`a${1}bc\\", []token.TokenType{token.TEMPLATE_HEAD, token.NUMBER, token.ILLEGAL, token.EOF}, "unterminated template literal at 1:6"},
+		{"1 @", []token.TokenType{token.NUMBER, token.ILLEGAL}, "unexpected character '@' at 1:3"},
+		{"1__0", []token.TokenType{token.ILLEGAL}, "invalid number literal 1__0 at 1:1"},
+		{`"\q"`, []token.TokenType{token.ILLEGAL}, `invalid escape sequence \q at 1:2`},
+		{`"abc\`, []token.TokenType{token.ILLEGAL, token.EOF}, "unterminated string literal at 1:1"},
+		{`'abc\`, []token.TokenType{token.ILLEGAL, token.EOF}, "unterminated string literal at 1:1"},
+		{"\"abc\\\nx", []token.TokenType{token.ILLEGAL, token.IDENT, token.EOF}, "unterminated string literal at 1:1"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expectedTypes {
+			tok := l.NextToken()
+			if tok.Type != expected {
+				t.Fatalf("%q: tokens[%d] - tokentype wrong. expected=%q, got=%q",
+					tt.input, i, expected, tok.Type)
+			}
+		}
+		if len(l.Errors()) != 1 || l.Errors()[0].Error() != tt.expectedError {
+			t.Errorf("%q: expected error %q, got=%v", tt.input, tt.expectedError, l.Errors())
+		}
+	}
+}
+
+func TestTemplateLiterals(t *testing.T) {
+	input := "`a${x}b${ {k: `in${1}`}[\"k\"] }c` tag`plain`"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.TEMPLATE_HEAD, "a"},
+		{token.IDENT, "x"},
+		{token.TEMPLATE_MIDDLE, "b"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "k"},
+		{token.COLON, ":"},
+		{token.TEMPLATE_HEAD, "in"},
+		{token.NUMBER, "1"},
+		{token.TEMPLATE_TAIL, ""},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "k"},
+		{token.RBRACKET, "]"},
+		{token.TEMPLATE_TAIL, "c"},
+		{token.IDENT, "tag"},
+		{token.TEMPLATE, "plain"},
+		{token.EOF, ""},
+	}
+
+	runTokenTest(t, input, tests)
+}
+
+func TestTemplateEscapes(t *testing.T) {
+	input := "`a\\`b` `\\${x}` `$` `tab\\t${1}\\u{41}\\\\` r\"${x}\\n\" r'a\"b' for\"s\""
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedRaw     string
+	}{
+		{token.TEMPLATE, "a`b", "a\\`b"},
+		{token.TEMPLATE, "${x}", "\\${x}"},
+		{token.TEMPLATE, "$", "$"},
+		{token.TEMPLATE_HEAD, "tab\t", "tab\\t"},
+		{token.NUMBER, "1", ""},
+		{token.TEMPLATE_TAIL, "A\\", "\\u{41}\\\\"},
+		{token.STRING, "${x}\\n", ""},
+		{token.STRING, `a"b`, ""},
+		{token.FOR, "for", ""},
+		{token.STRING, "s", ""},
+		{token.EOF, "", ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral || tok.Raw != tt.expectedRaw {
+			t.Fatalf("tests[%d] - expected %s %q (raw %q), got %s %q (raw %q)",
+				i, tt.expectedType, tt.expectedLiteral, tt.expectedRaw, tok.Type, tok.Literal, tok.Raw)
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected errors: %v", l.Errors())
+	}
+
+	l = New("`a\\q${1}b`")
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+	if len(l.Errors()) != 1 || l.Errors()[0].Error() != `invalid escape sequence \q at 1:3` {
+		t.Errorf("wrong errors. got=%v", l.Errors())
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// line comment
+	let a = 1; // trailing
+	/* block /* nested */ still comment */
+	/**/
+	/**
+	 * Adds two numbers.
+	 *
+	 * Returns their sum.
+	 */
+	let b = a / 2;
+	/** one line */`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.NUMBER, "1"},
+		{token.SEMICOLON, ";"},
+		{token.DOC_COMMENT, "Adds two numbers.\n\nReturns their sum."},
+		{token.LET, "let"},
+		{token.IDENT, "b"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "a"},
+		{token.SLASH, "/"},
+		{token.NUMBER, "2"},
+		{token.SEMICOLON, ";"},
+		{token.DOC_COMMENT, "one line"},
+		{token.EOF, ""},
+	}
+
+	runTokenTest(t, input, tests)
+
+	l := New("1 /* open /* inner */")
+	l.NextToken()
+	if tok := l.NextToken(); tok.Type != token.ILLEGAL {
+		t.Fatalf("unterminated comment - tokentype wrong. got=%q", tok.Type)
+	}
+	if len(l.Errors()) != 1 || l.Errors()[0].Error() != "unterminated block comment at 1:3" {
+		t.Errorf("wrong errors. got=%v", l.Errors())
+	}
+}
+
+func TestContextualKeywords(t *testing.T) {
+	input := `let map = get; print len of from fn`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "map"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "get"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "print"},
+		{token.IDENT, "len"},
+		{token.IDENT, "of"},
+		{token.IDENT, "from"},
+		{token.FUNCTION, "fn"},
+		{token.EOF, ""},
+	}
+
+	runTokenTest(t, input, tests)
+
+	of := token.Token{Type: token.IDENT, Literal: "of"}
+	if !token.IsContextual(of, token.OF) {
+		t.Errorf("of should be recognized as the OF contextual keyword")
+	}
+	if token.IsContextual(of, token.FROM) {
+		t.Errorf("of should not be recognized as the FROM contextual keyword")
+	}
+}
+
+func TestNewlineBefore(t *testing.T) {
+	input := "a b\nc /* one line */ d /* two\nlines */ e // end\n/** doc */ f\n/**\n * doc\n */ g"
+
+	tests := []struct {
+		literal       string
+		newlineBefore bool
+	}{
+		{"a", false},
+		{"b", false},
+		{"c", true},
+		{"d", false},
+		{"e", true},
+		{"doc", true},
+		{"f", true},
+		{"doc", true},
+		{"g", true},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.literal || tok.NewlineBefore != tt.newlineBefore {
+			t.Fatalf("tests[%d] - expected %q with NewlineBefore=%t, got %q with NewlineBefore=%t",
+				i, tt.literal, tt.newlineBefore, tok.Literal, tok.NewlineBefore)
+		}
+	}
+}
+
 // 辅助函数
+func TestClassKeywords(t *testing.T) {
+	input := `class A extends B { static s() { super.s() } }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.CLASS, "class"},
+		{token.IDENT, "A"},
+		{token.EXTENDS, "extends"},
+		{token.IDENT, "B"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "static"},
+		{token.IDENT, "s"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.SUPER, "super"},
+		{token.DOT, "."},
+		{token.IDENT, "s"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.RBRACE, "}"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	runTokenTest(t, input, tests)
+}
+
 func runTokenTest(t *testing.T, input string, tests []struct {
 	expectedType    token.TokenType
 	expectedLiteral string
@@ -1,13 +1,33 @@
 package main
 
 import (
+	"finger/evaluator"
+	"finger/lexer"
+	"finger/object"
+	"finger/parser"
 	"finger/repl"
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
 )
 
 func main() {
+	overflow := flag.String("overflow", evaluator.DefaultOverflowMode.String(), "integer overflow handling: wrap, error or promote")
+	flag.Parse()
+
+	mode, err := evaluator.ParseOverflowMode(*overflow)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	evaluator.DefaultOverflowMode = mode
+
+	// 指定了源文件时直接运行
+	if flag.NArg() > 0 {
+		os.Exit(runFile(flag.Arg(0)))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -16,4 +36,48 @@ func main() {
 	fmt.Printf("Hello %s! This is the Finger programming language!\n", user.Username)
 	fmt.Printf("Feel free to type in commands\n")
 	repl.Start(os.Stdin, os.Stdout)
-}
\ No newline at end of file
+}
+
+/*
+	运行一个源文件, 返回进程退出码
+*/
+func runFile(path string) int {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, msg)
+		}
+		return 1
+	}
+
+	evaluated := evaluator.Eval(program, object.NewEnvironment())
+
+	if errObj, ok := evaluated.(*object.Error); ok {
+		printError(path, errObj)
+		return 1
+	}
+
+	// 执行完顶层代码后运行事件循环, 直到所有异步任务完成
+	if errObj := evaluator.RunEventLoop(); errObj != nil {
+		printError(path, errObj)
+		return 1
+	}
+
+	return 0
+}
+
+func printError(path string, errObj *object.Error) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", path, errObj.Inspect())
+	// 顶层代码中的错误只有一帧, 不再重复打印位置
+	if len(errObj.Stack) > 0 {
+		fmt.Fprintln(os.Stderr, errObj.StackTrace())
+	}
+}
@@ -0,0 +1,120 @@
+package object
+
+import (
+	"finger/ast"
+)
+
+/*
+	类
+	方法沿继承链查找, 静态成员同样可以被子类继承
+*/
+type Class struct {
+	Name string
+	Super *Class // 父类, 没有时为nil
+	Constructor *Function // 没有显式构造函数时为nil
+	Methods map[string]*Function
+	Statics map[string]Object // 静态方法和静态字段
+	Fields []*ast.ClassMember // 实例字段, 每次创建实例时在Env中求值
+	Env *Environment // 类定义所在的环境
+	Doc string // 文档注释, 没有时为空字符串
+}
+
+func (c *Class) Type() ObjectType {
+	return CLASS_OBJ
+}
+
+func (c *Class) Inspect() string {
+	name := c.Name
+	if name == "" {
+		name = "(anonymous)"
+	}
+	if c.Super != nil {
+		return "class " + name + " extends " + c.Super.Inspect()[len("class "):]
+	}
+	return "class " + name
+}
+
+/*
+	沿继承链查找实例方法, 同时返回定义该方法的类
+*/
+func (c *Class) FindMethod(name string) (*Function, *Class) {
+	for class := c; class != nil; class = class.Super {
+		if method, ok := class.Methods[name]; ok {
+			return method, class
+		}
+	}
+	return nil, nil
+}
+
+/*
+	沿继承链查找构造函数, 同时返回定义该构造函数的类
+*/
+func (c *Class) FindConstructor() (*Function, *Class) {
+	for class := c; class != nil; class = class.Super {
+		if class.Constructor != nil {
+			return class.Constructor, class
+		}
+	}
+	return nil, nil
+}
+
+/*
+	沿继承链查找静态成员, 同时返回定义该成员的类
+*/
+func (c *Class) FindStatic(name string) (Object, *Class) {
+	for class := c; class != nil; class = class.Super {
+		if value, ok := class.Statics[name]; ok {
+			return value, class
+		}
+	}
+	return nil, nil
+}
+
+/*
+	检查c是否是class本身或它的子类
+*/
+func (c *Class) IsSubclassOf(class *Class) bool {
+	for current := c; current != nil; current = current.Super {
+		if current == class {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	类的实例, 字段按键为字符串的哈希表保存
+*/
+type Instance struct {
+	Class *Class
+	Fields *Hash
+}
+
+func (i *Instance) Type() ObjectType {
+	return INSTANCE_OBJ
+}
+
+func (i *Instance) Inspect() string {
+	if i.Class.Name == "" {
+		return i.Fields.Inspect()
+	}
+	return i.Class.Name + " " + i.Fields.Inspect()
+}
+
+/*
+	绑定了this的方法
+	Home是定义该方法的类, 方法中的super从Home的父类开始查找
+*/
+type BoundMethod struct {
+	Receiver Object
+	Fn *Function
+	Home *Class // 不是类的方法时为nil
+}
+
+func (bm *BoundMethod) Type() ObjectType {
+	return BOUND_METHOD_OBJ
+}
+
+func (bm *BoundMethod) Inspect() string {
+	return bm.Fn.Inspect()
+}
@@ -7,6 +7,7 @@ package object
 type Environment struct {
 	store map[string]Object
 	outer *Environment // 外层环境 用于闭包
+	pragmas map[string]any // 编译指示, 如 "use checked"; 创建时继承外层环境的, 闭包同样可见
 }
 
 /*
@@ -38,6 +39,20 @@ func (e *Environment) Set(name string, obj Object) Object {
 	return obj
 }
 
+/*
+	给已经存在的变量赋值, 沿外层环境向上查找定义该变量的环境
+	变量不存在时返回false
+*/
+func (e *Environment) Assign(name string, obj Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = obj
+			return true
+		}
+	}
+	return false
+}
+
 /*
 	创建一个新的封闭环境
 	实现闭包
@@ -45,5 +60,28 @@ func (e *Environment) Set(name string, obj Object) Object {
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	// 编译指示只在程序入口设置, 直接共享外层的map, 运算时无需沿环境链查找
+	env.pragmas = outer.pragmas
 	return env
 }
+
+/*
+	设置编译指示
+	map可能与内层环境共享, 因此复制后再修改, 已创建的内层环境不受影响
+*/
+func (e *Environment) SetPragma(name string, value any) {
+	pragmas := make(map[string]any, len(e.pragmas)+1)
+	for k, v := range e.pragmas {
+		pragmas[k] = v
+	}
+	pragmas[name] = value
+	e.pragmas = pragmas
+}
+
+/*
+	获取编译指示, 值在设置时已经解析好
+*/
+func (e *Environment) Pragma(name string) (any, bool) {
+	value, ok := e.pragmas[name]
+	return value, ok
+}
@@ -0,0 +1,193 @@
+package object
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+/*
+	结构相等
+	数组按元素逐个比较, 哈希表比较键值对而忽略插入顺序,
+	不同类型的值总是不相等, 包括数值相同的Integer与Float, 这与它们作为哈希表键时的行为一致;
+	函数等按引用比较。
+	可以处理循环引用: 正在比较的一对值再次出现时视为相等。
+*/
+func DeepEqual(a, b Object) bool {
+	return deepEqual(a, b, make(map[[2]Object]bool))
+}
+
+func deepEqual(a, b Object, visiting map[[2]Object]bool) bool {
+	if a == b {
+		return true
+	}
+
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *String, *Boolean, *Integer, *BigInt:
+		return hashKeysEqual(a, b)
+	case *Float:
+		return a.Value == b.(*Float).Value
+	case *Null, *Undefined:
+		return true
+	case *Array:
+		b := b.(*Array)
+		if len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		pair := [2]Object{a, b}
+		if visiting[pair] {
+			return true
+		}
+		visiting[pair] = true
+		defer delete(visiting, pair)
+
+		for i := range a.Elements {
+			if !deepEqual(a.Elements[i], b.Elements[i], visiting) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		b := b.(*Hash)
+		if a.Len() != b.Len() {
+			return false
+		}
+		pair := [2]Object{a, b}
+		if visiting[pair] {
+			return true
+		}
+		visiting[pair] = true
+		defer delete(visiting, pair)
+
+		for entry := a.head; entry != nil; entry = entry.next {
+			key, ok := AsHashable(entry.Key)
+			if !ok {
+				return false
+			}
+			other := b.find(key.HashKey(), key)
+			if other == nil || !deepEqual(entry.Value, other.Value, visiting) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+	检查对象能否作为哈希表的键
+	数组和哈希表只有冻结后才能作为键, 并且其中的元素也必须可以作为键, 不能有循环引用
+*/
+func AsHashable(obj Object) (Hashable, bool) {
+	hashable, ok := obj.(Hashable)
+	if !ok || !isHashable(obj, make(map[Object]bool)) {
+		return nil, false
+	}
+
+	return hashable, true
+}
+
+func isHashable(obj Object, visiting map[Object]bool) bool {
+	switch obj := obj.(type) {
+	case *Array:
+		if !obj.Frozen || visiting[obj] {
+			return false
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		for _, el := range obj.Elements {
+			if !isHashable(el, visiting) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		if !obj.Frozen || visiting[obj] {
+			return false
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		for entry := obj.head; entry != nil; entry = entry.next {
+			if !isHashable(entry.Value, visiting) {
+				return false
+			}
+		}
+		return true
+	default:
+		_, ok := obj.(Hashable)
+		return ok
+	}
+}
+
+/*
+	冻结对象: 冻结后的数组、哈希表和实例字段不可修改, 因此可以计算结构化的HashKey
+	会递归冻结其中的数组和哈希表
+*/
+func Freeze(obj Object) {
+	switch obj := obj.(type) {
+	case *Array:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, el := range obj.Elements {
+			Freeze(el)
+		}
+	case *Hash:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for entry := obj.head; entry != nil; entry = entry.next {
+			Freeze(entry.Value)
+		}
+	case *Instance:
+		Freeze(obj.Fields)
+	}
+}
+
+/*
+	数组的结构化HashKey, 由各元素的HashKey按顺序组合而成
+	只应在AsHashable检查通过后调用
+*/
+func (a *Array) HashKey() HashKey {
+	h := fnv.New64a()
+
+	for _, el := range a.Elements {
+		writeHashKey(h, el.(Hashable).HashKey())
+	}
+
+	return HashKey{Type: a.Type(), Value: h.Sum64()}
+}
+
+/*
+	哈希表的结构化HashKey, 与键值对的顺序无关
+	只应在AsHashable检查通过后调用
+*/
+func (h *Hash) HashKey() HashKey {
+	var sum uint64
+
+	for entry := h.head; entry != nil; entry = entry.next {
+		pairHash := fnv.New64a()
+		writeHashKey(pairHash, entry.Key.(Hashable).HashKey())
+		writeHashKey(pairHash, entry.Value.(Hashable).HashKey())
+		// 使用加法组合, 使结果与顺序无关
+		sum += pairHash.Sum64()
+	}
+
+	return HashKey{Type: h.Type(), Value: sum}
+}
+
+func writeHashKey(h interface{ Write([]byte) (int, error) }, key HashKey) {
+	var buf [8]byte
+
+	h.Write([]byte(key.Type))
+	binary.LittleEndian.PutUint64(buf[:], key.Value)
+	h.Write(buf[:])
+}
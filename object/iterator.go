@@ -0,0 +1,85 @@
+package object
+
+/*
+	迭代器协议
+	可迭代的值提供一个迭代器, 反复调用Next直到done为true
+	数组、字符串、哈希表和生成器可以直接迭代;
+	用户对象通过 Symbol.iterator 方法返回迭代器, 或者自己提供返回 {value, done} 的next方法
+	Symbol.iterator 的值是字符串token.ITERATOR, 作为普通的属性名保存在哈希表和类中
+*/
+
+/*
+	迭代器
+	Next返回下一个值以及迭代是否已结束, 结束时的值是生成器的返回值或nil; 出错时值为*Error
+	Close在迭代提前结束时调用, 返回关闭时发生的错误或nil; 不需要清理的迭代器为nil
+*/
+type Iterator struct {
+	Name string // 创建迭代器的函数名, 如 range
+	Next func() (Object, bool)
+	Close func() Object
+}
+
+func (it *Iterator) Type() ObjectType {
+	return ITERATOR_OBJ
+}
+
+func (it *Iterator) Inspect() string {
+	if it.Name == "" {
+		return "[iterator]"
+	}
+	return "[iterator " + it.Name + "]"
+}
+
+/*
+	提前结束迭代
+*/
+func (it *Iterator) Stop() Object {
+	if it.Close == nil {
+		return nil
+	}
+	return it.Close()
+}
+
+/*
+	数组迭代器, 每次读取当前的长度, 迭代期间追加的元素也会被访问
+*/
+func NewArrayIterator(arr *Array) *Iterator {
+	index := 0
+	return &Iterator{Name: "array", Next: func() (Object, bool) {
+		if index >= len(arr.Elements) {
+			return nil, true
+		}
+		index++
+		return arr.Elements[index-1], false
+	}}
+}
+
+/*
+	字符串迭代器, 按字符(Unicode码点)迭代
+*/
+func NewStringIterator(str *String) *Iterator {
+	chars := []rune(str.Value)
+	index := 0
+	return &Iterator{Name: "string", Next: func() (Object, bool) {
+		if index >= len(chars) {
+			return nil, true
+		}
+		index++
+		return &String{Value: string(chars[index-1])}, false
+	}}
+}
+
+/*
+	哈希表迭代器, 按插入顺序迭代创建迭代器时已有的键, 不包括原型中的键
+*/
+func NewHashIterator(hash *Hash) *Iterator {
+	pairs := hash.Pairs()
+	index := 0
+	return &Iterator{Name: "hash", Next: func() (Object, bool) {
+		if index >= len(pairs) {
+			return nil, true
+		}
+		index++
+		return pairs[index-1].Key, false
+	}}
+}
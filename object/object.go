@@ -3,8 +3,12 @@ package object
 import (
 	"bytes"
 	"finger/ast"
+	"finger/token"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
 )
 
@@ -16,8 +20,11 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ = "INTEGER"
+	FLOAT_OBJ   = "FLOAT"
+	BIGINT_OBJ  = "BIGINT"
 	BOOLEAN_OBJ = "BOOLEAN"
 	NULL_OBJ    = "NULL"
+	UNDEFINED_OBJ = "UNDEFINED"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	ERROR_OBJ = "ERROR"
 	FUNCTION_OBJ = "FUNCTION"
@@ -25,6 +32,25 @@ const (
 	BUILTIN_OBJ = "BUILTIN"
 	ARRAY_OBJ = "ARRAY"
 	HASH_OBJ = "HASH"
+	CLASS_OBJ = "CLASS"
+	INSTANCE_OBJ = "INSTANCE"
+	BOUND_METHOD_OBJ = "BOUND_METHOD"
+	BREAK_OBJ = "BREAK"
+	CONTINUE_OBJ = "CONTINUE"
+	GENERATOR_OBJ = "GENERATOR"
+	ITERATOR_OBJ = "ITERATOR"
+	PROMISE_OBJ = "PROMISE"
+)
+
+/*
+	运行时错误的种类, 捕获错误时作为错误对象的kind字段
+*/
+const (
+	ERROR_KIND = "Error" // 没有更具体种类的错误
+	TYPE_ERROR = "TypeError" // 值的类型不支持该操作
+	REFERENCE_ERROR = "ReferenceError" // 引用了不存在的变量
+	RANGE_ERROR = "RangeError" // 数值或索引超出允许的范围
+	BUILTIN_ERROR = "BuiltinError" // 内置函数报告的错误
 )
 
 /*
@@ -39,8 +65,11 @@ type Object interface {
 /*
 	finger支持
 	Integer
+	Float
+	BigInt
 	Boolean
 	Null
+	Undefined
 */
 
 type Integer struct {
@@ -55,6 +84,61 @@ func (i *Integer) Type() ObjectType {
 	return INTEGER_OBJ
 }
 
+type Float struct {
+	Value float64
+}
+
+/*
+	浮点数的字符串表示
+	整数值保留 .0 以区别于Integer, 过大或过小的值使用指数形式
+*/
+func (f *Float) Inspect() string {
+	value := f.Value
+
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	}
+
+	abs := math.Abs(value)
+	if abs >= 1e21 || (abs != 0 && abs < 1e-6) {
+		str := strconv.FormatFloat(value, 'e', -1, 64)
+		// Go会把指数补齐为两位(1e-09), 去掉多余的0
+		str = strings.Replace(str, "e-0", "e-", 1)
+		return strings.Replace(str, "e+0", "e+", 1)
+	}
+
+	str := strconv.FormatFloat(value, 'f', -1, 64)
+	if !strings.Contains(str, ".") {
+		str += ".0"
+	}
+
+	return str
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+/*
+	任意精度整数
+*/
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Inspect() string {
+	return b.Value.String() + "n"
+}
+
+func (b *BigInt) Type() ObjectType {
+	return BIGINT_OBJ
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -77,6 +161,43 @@ func (n *Null) Type() ObjectType {
 	return NULL_OBJ
 }
 
+/*
+	undefined表示"没有值": 缺失的哈希键、越界的数组索引、缺失的参数
+	null则表示"值为空", 由程序显式给出
+*/
+type Undefined struct {}
+
+func (u *Undefined) Inspect() string {
+	return "undefined"
+}
+
+func (u *Undefined) Type() ObjectType {
+	return UNDEFINED_OBJ
+}
+
+/*
+	break和continue语句产生的信号, 在所在的循环中被消耗
+*/
+type Break struct {}
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+
+type Continue struct {}
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+
 type ReturnValue struct {
 	Value Object
 }
@@ -91,9 +212,46 @@ func (rv *ReturnValue) Type() ObjectType {
 
 type Error struct {
 	Message string
+	Kind string // 错误种类, 为空时是ERROR_KIND
+	Pos token.Position // 出错的源码位置, 未知时为零值
+	Value Object // throw抛出的值, 运行时错误为nil
+	Stack []StackFrame // 创建错误时的调用栈, 最外层的调用在前
+	Uncatchable bool // 不能被catch捕获, 用于关闭生成器时从暂停处提前返回
+}
+
+/*
+	调用栈中的一帧: 被调用的函数及调用它的位置
+*/
+type StackFrame struct {
+	Function string
+	Pos token.Position // 调用位置
+}
+
+/*
+	返回错误的调用栈, 最内层的调用在前, 每行形如 "    at name (line:column)"
+	每一帧的位置是该函数中执行到的位置, 即出错位置或调用下一层函数的位置
+	最外层的代码记为<main>, 既没有调用栈也没有位置时返回空字符串
+*/
+func (e *Error) StackTrace() string {
+	if len(e.Stack) == 0 && !e.Pos.IsValid() {
+		return ""
+	}
+
+	lines := []string{}
+	pos := e.Pos
+	for i := len(e.Stack) - 1; i >= 0; i-- {
+		lines = append(lines, "    at "+e.Stack[i].Function+" ("+pos.String()+")")
+		pos = e.Stack[i].Pos
+	}
+	lines = append(lines, "    at <main> ("+pos.String()+")")
+
+	return strings.Join(lines, "\n")
 }
 
 func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Message + " at " + e.Pos.String()
+	}
 	return "ERROR: " + e.Message
 }
 
@@ -105,6 +263,10 @@ type Function struct {
 	Parameters []*ast.Identifier
 	Body *ast.BlockStatement
 	Env *Environment
+	Doc string // 文档注释, 没有时为空字符串
+	Name string // 函数名, 用于调用栈; 匿名函数为空字符串
+	Generator bool // 是否是生成器函数
+	Async bool // 是否是async函数
 }
 
 func (f *Function) Type() ObjectType {
@@ -120,7 +282,13 @@ func (f *Function) Inspect() string {
 		params = append(params, p.String())
 	}
 
+	if f.Async {
+		out.WriteString("async ")
+	}
 	out.WriteString("fn")
+	if f.Generator {
+		out.WriteString("*")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
@@ -130,6 +298,27 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+/*
+	生成器, 调用生成器函数时创建, 函数体在第一次调用next时才开始执行
+	执行过程由求值器提供的Resume和Close驱动
+*/
+type Generator struct {
+	Name string // 生成器函数名, 匿名时为空字符串
+	Resume func(value Object) (Object, bool) // 恢复执行到下一个yield, 返回产出的值以及生成器是否已结束
+	Close func(value Object) (Object, bool) // 从暂停处提前返回value, 期间会执行finally块
+}
+
+func (g *Generator) Type() ObjectType {
+	return GENERATOR_OBJ
+}
+
+func (g *Generator) Inspect() string {
+	if g.Name == "" {
+		return "[generator]"
+	}
+	return "[generator " + g.Name + "]"
+}
+
 type String struct {
 	Value string
 }
@@ -161,6 +350,8 @@ func (b *Builtin) Inspect() string {
 
 type Array struct {
 	Elements []Object
+	Frozen bool // 冻结后不可修改, 可以作为哈希表的键
+	Raw *Array // 传给模板标签函数的文本片段数组对应的原始文本, 通过 strings.raw 读取; 其他数组为nil
 }
 
 func (a *Array) Type() ObjectType {
@@ -211,26 +402,196 @@ func (s *String) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write(b.Value.Bytes())
+	if b.Value.Sign() < 0 {
+		h.Write([]byte{'-'})
+	}
+
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
 type HashPair struct {
 	Key Object
 	Value Object
 }
 
+/*
+	哈希表中的一个条目, 通过双向链表维护插入顺序
+*/
+type hashEntry struct {
+	HashPair
+	prev *hashEntry
+	next *hashEntry
+}
+
+/*
+	哈希表
+	按插入顺序保存键值对: index负责O(1)查找, 双向链表负责顺序遍历和O(1)删除。
+	不同的键可能得到相同的HashKey(例如FNV-64a碰撞), 因此同一个HashKey下保存一个桶,
+	查找时再比较键本身, 不会互相覆盖。
+	零值可以直接使用。
+*/
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	index map[HashKey][]*hashEntry
+	head *hashEntry
+	tail *hashEntry
+	length int
+	Frozen bool // 冻结后不可修改, 可以作为哈希表的键
+	Proto *Hash // 原型, 读取属性时自身没有的键沿原型链查找; 没有原型时为nil
+}
+
+/*
+	创建一个空的哈希表
+*/
+func NewHash() *Hash {
+	return &Hash{index: make(map[HashKey][]*hashEntry)}
 }
 
 func (h *Hash) Type() ObjectType {
 	return HASH_OBJ
 }
 
+/*
+	获取键对应的值
+*/
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	entry := h.find(key.HashKey(), key)
+	if entry == nil {
+		return nil, false
+	}
+
+	return entry.Value, true
+}
+
+/*
+	沿原型链查找键, 先查找自身再依次查找各级原型
+*/
+func (h *Hash) Lookup(key Hashable) (Object, bool) {
+	for hash := h; hash != nil; hash = hash.Proto {
+		if value, ok := hash.Get(key); ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+/*
+	检查proto是否在原型链上(不包括自身)
+*/
+func (h *Hash) InheritsFrom(proto *Hash) bool {
+	for hash := h.Proto; hash != nil; hash = hash.Proto {
+		if hash == proto {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	设置键值对, 已存在的键保持原来的位置
+*/
+func (h *Hash) Set(key Hashable, value Object) {
+	h.set(key.HashKey(), key, value)
+}
+
+func (h *Hash) set(hashed HashKey, key Object, value Object) {
+	if entry := h.find(hashed, key); entry != nil {
+		entry.Value = value
+		return
+	}
+
+	if h.index == nil {
+		h.index = make(map[HashKey][]*hashEntry)
+	}
+
+	entry := &hashEntry{HashPair: HashPair{Key: key, Value: value}, prev: h.tail}
+	if h.tail == nil {
+		h.head = entry
+	} else {
+		h.tail.next = entry
+	}
+	h.tail = entry
+	h.index[hashed] = append(h.index[hashed], entry)
+	h.length++
+}
+
+/*
+	删除键, 返回键是否存在
+*/
+func (h *Hash) Delete(key Hashable) bool {
+	hashed := key.HashKey()
+	bucket := h.index[hashed]
+
+	for i, entry := range bucket {
+		if !hashKeysEqual(entry.Key, key) {
+			continue
+		}
+
+		// 从桶中移除
+		if len(bucket) == 1 {
+			delete(h.index, hashed)
+		} else {
+			h.index[hashed] = append(bucket[:i:i], bucket[i+1:]...)
+		}
+
+		// 从链表中移除
+		if entry.prev == nil {
+			h.head = entry.next
+		} else {
+			entry.prev.next = entry.next
+		}
+		if entry.next == nil {
+			h.tail = entry.prev
+		} else {
+			entry.next.prev = entry.prev
+		}
+		h.length--
+
+		return true
+	}
+
+	return false
+}
+
+/*
+	返回键值对的数量
+*/
+func (h *Hash) Len() int {
+	return h.length
+}
+
+/*
+	按插入顺序返回所有键值对
+*/
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, 0, h.length)
+
+	for entry := h.head; entry != nil; entry = entry.next {
+		pairs = append(pairs, entry.HashPair)
+	}
+
+	return pairs
+}
+
+func (h *Hash) find(hashed HashKey, key Object) *hashEntry {
+	for _, entry := range h.index[hashed] {
+		if hashKeysEqual(entry.Key, key) {
+			return entry
+		}
+	}
+
+	return nil
+}
+
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
 
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	for entry := h.head; entry != nil; entry = entry.next {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", entry.Key.Inspect(), entry.Value.Inspect()))
 	}
 
 	out.WriteString("{")
@@ -241,6 +602,30 @@ func (h *Hash) Inspect() string {
 }
 
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
+/*
+	比较两个HashKey相同的键是否真的是同一个键
+*/
+func hashKeysEqual(a, b Object) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *BigInt:
+		return a.Value.Cmp(b.(*BigInt).Value) == 0
+	case *Array, *Hash:
+		return DeepEqual(a, b)
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,172 @@
+package object
+
+import (
+	"finger/token"
+	"testing"
+)
+
+func TestHashPreservesInsertionOrder(t *testing.T) {
+	h := NewHash()
+	h.Set(&String{Value: "b"}, &Integer{Value: 1})
+	h.Set(&String{Value: "a"}, &Integer{Value: 2})
+	h.Set(&Integer{Value: 3}, &Integer{Value: 3})
+	// 覆盖已有的键不改变其位置
+	h.Set(&String{Value: "b"}, &Integer{Value: 4})
+
+	expected := `{b: 4, a: 2, 3: 3}`
+	if h.Inspect() != expected {
+		t.Fatalf("hash.Inspect() wrong. expected=%q, got=%q", expected, h.Inspect())
+	}
+
+	if !h.Delete(&String{Value: "a"}) {
+		t.Fatalf("hash.Delete() returned false for existing key")
+	}
+	if h.Delete(&String{Value: "a"}) {
+		t.Fatalf("hash.Delete() returned true for deleted key")
+	}
+	h.Set(&String{Value: "a"}, &Integer{Value: 5})
+
+	expected = `{b: 4, 3: 3, a: 5}`
+	if h.Inspect() != expected {
+		t.Fatalf("hash.Inspect() wrong. expected=%q, got=%q", expected, h.Inspect())
+	}
+	if h.Len() != 3 {
+		t.Fatalf("hash.Len() wrong. expected=3, got=%d", h.Len())
+	}
+}
+
+func TestHashKeyCollision(t *testing.T) {
+	h := &Hash{}
+	collided := HashKey{Type: STRING_OBJ, Value: 42}
+	first := &String{Value: "first"}
+	second := &String{Value: "second"}
+
+	// 模拟两个不同字符串的FNV-64a哈希值相同
+	h.set(collided, first, &Integer{Value: 1})
+	h.set(collided, second, &Integer{Value: 2})
+
+	if h.Len() != 2 {
+		t.Fatalf("colliding keys overwrote each other. len=%d", h.Len())
+	}
+
+	for key, want := range map[*String]int64{first: 1, second: 2} {
+		entry := h.find(collided, key)
+		if entry == nil {
+			t.Fatalf("key %q not found", key.Value)
+		}
+		if got := entry.Value.(*Integer).Value; got != want {
+			t.Errorf("key %q has wrong value. expected=%d, got=%d", key.Value, want, got)
+		}
+	}
+}
+
+func TestDeepEqualHandlesCycles(t *testing.T) {
+	a := &Array{}
+	a.Elements = []Object{&Integer{Value: 1}, a}
+	b := &Array{}
+	b.Elements = []Object{&Integer{Value: 1}, b}
+
+	if !DeepEqual(a, b) {
+		t.Errorf("DeepEqual returned false for structurally equal cycles")
+	}
+
+	c := &Array{}
+	c.Elements = []Object{&Integer{Value: 2}, c}
+	if DeepEqual(a, c) {
+		t.Errorf("DeepEqual returned true for different cycles")
+	}
+
+	Freeze(a)
+	if _, ok := AsHashable(a); ok {
+		t.Errorf("cyclic array should not be hashable")
+	}
+}
+
+func TestDeepEqualMixedNumbers(t *testing.T) {
+	one := &Integer{Value: 1}
+	oneFloat := &Float{Value: 1.0}
+
+	if DeepEqual(one, oneFloat) || DeepEqual(oneFloat, one) {
+		t.Errorf("DeepEqual treated INTEGER 1 and FLOAT 1.0 as equal")
+	}
+	if !DeepEqual(oneFloat, &Float{Value: 1.0}) {
+		t.Errorf("DeepEqual returned false for equal floats")
+	}
+
+	// 相等的值作为键时必须表现一致
+	ints := &Array{Elements: []Object{&Integer{Value: 1}}, Frozen: true}
+	floats := &Array{Elements: []Object{&Float{Value: 1.0}}, Frozen: true}
+	if DeepEqual(ints, floats) {
+		t.Errorf("DeepEqual treated [1] and [1.0] as equal")
+	}
+	if _, ok := AsHashable(ints); !ok {
+		t.Errorf("frozen [1] should be hashable")
+	}
+	if _, ok := AsHashable(floats); ok {
+		t.Errorf("frozen [1.0] should not be hashable")
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	err := &Error{
+		Message: "division by zero",
+		Pos:     token.Position{Line: 1, Column: 20},
+		Stack: []StackFrame{
+			{Function: "outer", Pos: token.Position{Line: 5, Column: 6}},
+			{Function: "inner", Pos: token.Position{Line: 2, Column: 10}},
+		},
+	}
+
+	expected := "    at inner (1:20)\n    at outer (2:10)\n    at <main> (5:6)"
+	if trace := err.StackTrace(); trace != expected {
+		t.Errorf("wrong stack trace. expected=%q, got=%q", expected, trace)
+	}
+
+	topLevel := &Error{Message: "oops", Pos: token.Position{Line: 3, Column: 1}}
+	if trace := topLevel.StackTrace(); trace != "    at <main> (3:1)" {
+		t.Errorf("wrong top-level stack trace. got=%q", trace)
+	}
+
+	if trace := (&Error{Message: "oops"}).StackTrace(); trace != "" {
+		t.Errorf("expected an empty stack trace without position. got=%q", trace)
+	}
+}
+
+func TestBuiltinIterators(t *testing.T) {
+	collect := func(it *Iterator) []string {
+		values := []string{}
+		for {
+			value, done := it.Next()
+			if done {
+				return values
+			}
+			values = append(values, value.Inspect())
+		}
+	}
+
+	arr := &Array{Elements: []Object{&Integer{Value: 1}}}
+	arrayIter := NewArrayIterator(arr)
+	if value, done := arrayIter.Next(); done || value.Inspect() != "1" {
+		t.Fatalf("wrong first array element. got=%v done=%t", value, done)
+	}
+	// 迭代期间追加的元素也会被访问
+	arr.Elements = append(arr.Elements, &Integer{Value: 2})
+	if got := collect(arrayIter); len(got) != 1 || got[0] != "2" {
+		t.Errorf("appended element not visited. got=%v", got)
+	}
+
+	if got := collect(NewStringIterator(&String{Value: "日本"})); len(got) != 2 || got[0] != "日" || got[1] != "本" {
+		t.Errorf("wrong string iteration. got=%v", got)
+	}
+
+	h := NewHash()
+	h.Set(&String{Value: "b"}, &Integer{Value: 1})
+	h.Set(&String{Value: "a"}, &Integer{Value: 2})
+	if got := collect(NewHashIterator(h)); len(got) != 2 || got[0] != "b" || got[1] != "a" {
+		t.Errorf("wrong hash iteration. got=%v", got)
+	}
+
+	if err := arrayIter.Stop(); err != nil {
+		t.Errorf("Stop without Close should return nil. got=%v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package object
+
+/*
+	Promise
+	表示一个异步操作的最终结果, 从等待状态敲定为兑现或拒绝后不再改变
+	敲定时登记的回调由求值器放入微任务队列, 按登记的顺序执行
+*/
+
+type PromiseState int
+
+const (
+	PENDING PromiseState = iota
+	FULFILLED
+	REJECTED
+)
+
+type Promise struct {
+	State PromiseState
+	Value Object // 兑现的值或拒绝的原因, 等待时为nil
+	Handled bool // 是否登记过处理结果的回调, 没有处理的拒绝在事件循环结束时报告
+	Reactions []func() // 等待敲定的回调, 敲定后清空
+}
+
+func (p *Promise) Type() ObjectType {
+	return PROMISE_OBJ
+}
+
+func (p *Promise) Inspect() string {
+	switch p.State {
+	case FULFILLED:
+		return "Promise {" + p.Value.Inspect() + "}"
+	case REJECTED:
+		return "Promise {<rejected> " + p.Value.Inspect() + "}"
+	default:
+		return "Promise {<pending>}"
+	}
+}
@@ -0,0 +1,231 @@
+package parser
+
+/*
+	类的语法
+	class Name extends Base {
+		field = value
+		constructor(params) {...}
+		method(params) {...}
+		*generator(params) {...}
+		async method(params) {...}
+		[expr](params) {...}
+		static name(params) {...}
+	}
+*/
+
+import (
+	"finger/ast"
+	"finger/token"
+)
+
+/*
+	类声明语句解析器
+*/
+func (p *Parser) parseClassStatement() *ast.ClassStatement {
+	stmt := &ast.ClassStatement{Token: p.curToken}
+
+	stmt.Class = p.parseClassLiteral()
+	if stmt.Class == nil {
+		return nil
+	}
+
+	p.endStatement()
+
+	return stmt
+}
+
+/*
+	类表达式解析器, 如 let Point = class {...}
+*/
+func (p *Parser) parseClassExpression() ast.Expression {
+	if lit := p.parseClassLiteral(); lit != nil {
+		return lit
+	}
+	return nil
+}
+
+/*
+	类字面量解析器, curToken停在右花括号上
+*/
+func (p *Parser) parseClassLiteral() *ast.ClassLiteral {
+	lit := &ast.ClassLiteral{Token: p.curToken, Doc: p.curDoc}
+
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		lit.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if p.peekTokenIs(token.EXTENDS) {
+		p.nextToken()
+		p.nextToken()
+		lit.SuperClass = p.parseExpression(LOWSET)
+		if lit.SuperClass == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			p.addError(UnexpectedToken, p.curToken, []token.TokenType{token.RBRACE}, "expected } before end of input")
+			return nil
+		}
+		// 成员之间多余的分号
+		if p.curTokenIs(token.SEMICOLON) {
+			p.nextToken()
+			continue
+		}
+
+		member := p.parseClassMember()
+		if member == nil {
+			return nil
+		}
+
+		if member.Method && !member.Static && member.Key == nil && member.Name.Value == "constructor" {
+			if lit.Constructor != nil {
+				p.addError(UnexpectedToken, member.Name.Token, nil, "duplicate constructor in class")
+				return nil
+			}
+			if member.Value.(*ast.FunctionLiteral).Generator {
+				p.addError(UnexpectedToken, member.Name.Token, nil, "constructor cannot be a generator")
+				return nil
+			}
+			if member.Value.(*ast.FunctionLiteral).Async {
+				p.addError(UnexpectedToken, member.Name.Token, nil, "constructor cannot be async")
+				return nil
+			}
+			lit.Constructor = member.Value.(*ast.FunctionLiteral)
+		} else {
+			lit.Members = append(lit.Members, member)
+		}
+
+		p.nextToken()
+	}
+
+	return lit
+}
+
+/*
+	类成员解析器, curToken是成员名或static
+	方法解析完后curToken停在方法体的右花括号上, 字段解析完后停在字段的末尾
+*/
+func (p *Parser) parseClassMember() *ast.ClassMember {
+	member := &ast.ClassMember{}
+	doc := p.curDoc
+
+	// static本身也可以作为成员名, 如 static() {...}
+	if token.IsContextual(p.curToken, token.STATIC) &&
+		(p.peekTokenIs(token.IDENT) || token.IsKeyword(p.peekToken.Literal) ||
+			p.peekTokenIs(token.ASTERISK) || p.peekTokenIs(token.LBRACKET)) {
+		member.Static = true
+		p.nextToken()
+	}
+
+	// async方法 async name() {...}
+	async := false
+	if p.curTokenIs(token.ASYNC) &&
+		(p.peekTokenIs(token.IDENT) || token.IsKeyword(p.peekToken.Literal) ||
+			p.peekTokenIs(token.ASTERISK) || p.peekTokenIs(token.LBRACKET)) {
+		async = true
+		p.nextToken()
+	}
+
+	// 生成器方法 *name() {...}
+	generator := false
+	if p.curTokenIs(token.ASTERISK) {
+		if async {
+			p.addError(UnexpectedToken, p.curToken, nil, "async generators are not supported")
+			return nil
+		}
+		generator = true
+		p.nextToken()
+	}
+
+	switch {
+	// 计算成员名 [expr], 如 [Symbol.iterator]() {...}
+	case p.curTokenIs(token.LBRACKET):
+		tok := p.curToken
+		p.nextToken()
+		member.Key = p.parseExpression(LOWSET)
+		if member.Key == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		member.Name = &ast.Identifier{Token: tok, Value: "[" + member.Key.String() + "]"}
+	case p.curTokenIs(token.IDENT) || token.IsKeyword(p.curToken.Literal):
+		member.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	default:
+		p.addError(UnexpectedToken, p.curToken, []token.TokenType{token.IDENT},
+			"expected class member name, got %s instead", p.curToken.Type)
+		return nil
+	}
+
+	if member.Key != nil && !p.peekTokenIs(token.LPAREN) {
+		p.addError(UnexpectedToken, member.Name.Token, nil, "computed names are only supported for methods")
+		return nil
+	}
+	if (generator || async) && !p.peekTokenIs(token.LPAREN) {
+		p.peekErrors(token.LPAREN)
+		return nil
+	}
+
+	switch {
+	case p.peekTokenIs(token.LPAREN):
+		method := p.parseMethodLiteral(generator, async)
+		if method == nil {
+			return nil
+		}
+		if method.Doc == "" {
+			method.Doc = doc
+		}
+		member.Method = true
+		member.Value = method
+	case p.peekTokenIs(token.ASSIGN):
+		p.nextToken()
+		p.nextToken()
+		member.Value = p.parseExpression(LOWSET)
+		if member.Value == nil {
+			return nil
+		}
+		p.endStatement()
+	default:
+		p.endStatement()
+	}
+
+	return member
+}
+
+/*
+	new表达式解析器
+	类表达式按成员访问的优先级解析, 因此 new a.B(1) 创建的是a.B的实例
+*/
+func (p *Parser) parseNewExpression() ast.Expression {
+	exp := &ast.NewExpression{Token: p.curToken, Arguments: []ast.Expression{}}
+
+	p.nextToken()
+	exp.Class = p.parseExpression(CALL)
+	if exp.Class == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		exp.Arguments = p.parseCallArguments()
+		if exp.Arguments == nil {
+			return nil
+		}
+	}
+
+	return exp
+}
+
+func (p *Parser) parseThisExpression() ast.Expression {
+	return &ast.ThisExpression{Token: p.curToken}
+}
+
+func (p *Parser) parseSuperExpression() ast.Expression {
+	return &ast.SuperExpression{Token: p.curToken}
+}
@@ -0,0 +1,248 @@
+package parser
+
+/*
+	循环与异常处理语句
+*/
+
+import (
+	"finger/ast"
+	"finger/token"
+)
+
+/*
+	while语句解析器
+*/
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWSET)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.loopDepth++
+	stmt.Body = p.parseBlockStatement()
+	p.loopDepth--
+
+	p.endStatement()
+
+	return stmt
+}
+
+/*
+	for...of语句解析器
+	for (let x of xs) {...} 每次迭代声明新的变量; for (x of xs) {...} 赋值给已有的变量
+*/
+func (p *Parser) parseForOfStatement() *ast.ForOfStatement {
+	stmt := &ast.ForOfStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.LET) {
+		p.nextToken()
+		stmt.Declare = true
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !token.IsContextual(p.peekToken, token.OF) {
+		p.addError(UnexpectedToken, p.peekToken, []token.TokenType{token.OF},
+			"expected of after the loop variable, got %s instead", p.peekToken.Type)
+		return nil
+	}
+	p.nextToken()
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWSET)
+	if stmt.Iterable == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.loopDepth++
+	stmt.Body = p.parseBlockStatement()
+	p.loopDepth--
+
+	p.endStatement()
+
+	return stmt
+}
+
+/*
+	break语句解析器, 只能出现在循环中
+*/
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if p.loopDepth == 0 {
+		p.addError(UnexpectedToken, p.curToken, nil, "break outside of a loop")
+		return nil
+	}
+
+	p.endStatement()
+
+	return stmt
+}
+
+/*
+	continue语句解析器, 只能出现在循环中
+*/
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if p.loopDepth == 0 {
+		p.addError(UnexpectedToken, p.curToken, nil, "continue outside of a loop")
+		return nil
+	}
+
+	p.endStatement()
+
+	return stmt
+}
+
+/*
+	throw语句解析器
+	与return不同, throw之后不能换行, 必须在同一行给出抛出的值
+*/
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	if p.statementCanEnd() {
+		p.addError(UnexpectedToken, p.peekToken, p.expressionStarts(), "expected an expression on the same line after throw")
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWSET)
+	if stmt.Value == nil {
+		return nil
+	}
+
+	p.endStatement()
+
+	return stmt
+}
+
+/*
+	try语句解析器
+*/
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		// catch的参数可以省略: catch {...}
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			stmt.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Handler = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finalizer = p.parseBlockStatement()
+	}
+
+	if stmt.Handler == nil && stmt.Finalizer == nil {
+		p.peekErrors(token.CATCH, token.FINALLY)
+		return nil
+	}
+
+	p.endStatement()
+
+	return stmt
+}
+
+/*
+	yield表达式解析器, 只能出现在生成器函数体中
+	yield之后在语句结束处或者右括号、逗号等之前可以省略产出的值
+*/
+func (p *Parser) parseYieldExpression() ast.Expression {
+	exp := &ast.YieldExpression{Token: p.curToken}
+
+	if !p.inGenerator {
+		p.addError(UnexpectedToken, p.curToken, nil, "yield outside of a generator function")
+		return nil
+	}
+
+	if p.peekTokenIs(token.ASTERISK) {
+		p.nextToken()
+		exp.Delegate = true
+	}
+
+	if !exp.Delegate && (p.statementCanEnd() || p.peekTokenIs(token.RPAREN) ||
+		p.peekTokenIs(token.RBRACKET) || p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.COLON)) {
+		return exp
+	}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWSET)
+	if exp.Value == nil {
+		return nil
+	}
+
+	return exp
+}
+
+/*
+	await表达式解析器, 只能出现在async函数体或顶层代码中
+	await与前缀运算符的优先级相同, await a + b 等价于 (await a) + b
+*/
+func (p *Parser) parseAwaitExpression() ast.Expression {
+	exp := &ast.AwaitExpression{Token: p.curToken}
+
+	if !p.inAsync {
+		p.addError(UnexpectedToken, p.curToken, nil, "await is only valid in async functions and at the top level")
+		return nil
+	}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(PREFIX)
+	if exp.Value == nil {
+		return nil
+	}
+
+	return exp
+}
@@ -0,0 +1,221 @@
+package parser
+
+/*
+	语法错误与错误恢复
+	遇到错误后进入恐慌模式: 不再记录新的错误, 跳过词法单元直到语句边界后继续解析
+	这样一个错误只报告一次, 而且可以在同一个文件中报告多个互不相关的错误
+*/
+
+import (
+	"finger/ast"
+	"finger/token"
+	"fmt"
+	"sort"
+)
+
+// 一次解析最多记录的错误数, 超过后停止解析
+const MaxErrors = 25
+
+/*
+	语法错误的种类, 便于工具对错误分组
+*/
+type ErrorKind int
+
+const (
+	UnexpectedToken ErrorKind = iota // 遇到了不符合语法的词法单元
+	MissingPrefixParser // 词法单元不能作为表达式的开头
+	InvalidLiteral // 字面量无法解析, 如超出范围的整数
+	IllegalToken // 词法分析器产生的非法词法单元
+	TooManyErrors // 错误数达到上限, 之后的内容没有解析
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case UnexpectedToken:
+		return "unexpected token"
+	case MissingPrefixParser:
+		return "missing prefix parser"
+	case InvalidLiteral:
+		return "invalid literal"
+	case IllegalToken:
+		return "illegal token"
+	case TooManyErrors:
+		return "too many errors"
+	default:
+		return "unknown"
+	}
+}
+
+/*
+	源码中的一段区间, End是区间结束之后的位置
+*/
+type Span struct {
+	Start token.Position
+	End   token.Position
+}
+
+/*
+	语法错误
+	记录出错的区间、错误种类、实际遇到的词法单元以及此处可以接受的词法单元
+*/
+type ParseError struct {
+	Span     Span              // 出错的区间, 通常是Found所在的区间
+	Kind     ErrorKind
+	Found    token.Token       // 实际遇到的词法单元
+	Expected []token.TokenType // 此处可以接受的词法单元, 为空表示没有确定的集合
+	Message  string
+}
+
+/*
+	返回错误信息, 与原来字符串形式的错误保持一致
+*/
+func (e *ParseError) Error() string {
+	if !e.Span.Start.IsValid() {
+		return e.Message
+	}
+	return e.Message + " at " + e.Span.Start.String()
+}
+
+// 可以开始一条新语句的关键字, 错误恢复时作为同步点
+var statementKeywords = map[token.TokenType]bool{
+	token.LET:      true,
+	token.CONST:    true,
+	token.RETURN:   true,
+	token.CLASS:    true,
+	token.IF:       true,
+	token.FOR:      true,
+	token.WHILE:    true,
+	token.DO:       true,
+	token.SWITCH:   true,
+	token.TRY:      true,
+	token.THROW:    true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+	token.IMPORT:   true,
+	token.EXPORT:   true,
+}
+
+/*
+	记录一个语法错误
+	恐慌模式中的错误是前一个错误的连锁反应, 不再记录
+*/
+func (p *Parser) addError(kind ErrorKind, found token.Token, expected []token.TokenType, format string, args ...interface{}) {
+	if p.panicking || p.tooManyErrors() {
+		return
+	}
+	p.panicking = true
+
+	p.appendError(&ParseError{
+		Span:     Span{Start: found.Pos, End: found.End},
+		Kind:     kind,
+		Found:    found,
+		Expected: expected,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+/*
+	追加一个错误, 同一位置只保留第一个错误, 错误过多时追加一条提示
+*/
+func (p *Parser) appendError(err *ParseError) {
+	for _, existing := range p.errors {
+		if existing.Span.Start.IsValid() && existing.Span.Start == err.Span.Start {
+			return
+		}
+	}
+
+	if len(p.errors) == MaxErrors-1 {
+		err = &ParseError{Span: err.Span, Kind: TooManyErrors, Found: err.Found, Message: "too many errors"}
+	}
+	p.errors = append(p.errors, err)
+}
+
+/*
+	检查错误数是否已经达到上限
+*/
+func (p *Parser) tooManyErrors() bool {
+	return len(p.errors) >= MaxErrors
+}
+
+/*
+	收集词法分析器新产生的错误, 使词法错误和语法错误按出现顺序排列
+	词法错误都产生于刚读入的peekToken
+*/
+func (p *Parser) collectLexerErrors() {
+	errors := p.l.Errors()
+	for _, err := range errors[p.lexerErrors:] {
+		if !p.tooManyErrors() {
+			p.appendError(&ParseError{
+				Span:    Span{Start: err.Pos, End: p.peekToken.End},
+				Kind:    IllegalToken,
+				Found:   p.peekToken,
+				Message: err.Message,
+			})
+		}
+	}
+	p.lexerErrors = len(errors)
+}
+
+/*
+	返回可以作为表达式开头的词法单元, 按名称排序
+*/
+func (p *Parser) expressionStarts() []token.TokenType {
+	starts := make([]token.TokenType, 0, len(p.prefixParseFns))
+	for t := range p.prefixParseFns {
+		if t != token.ILLEGAL {
+			starts = append(starts, t)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	return starts
+}
+
+/*
+	恐慌模式的错误恢复: 跳过词法单元直到语句边界
+	边界为当前层的分号(curToken), 或者下一个词法单元是右花括号、EOF或位于新的一行
+	出错语句内部未闭合的括号中的这些词法单元不作为边界
+	语句关键字不会出现在表达式中间, 在任何深度都作为边界, 此时认为出错语句中的括号都已闭合
+	depth是出错语句开始之前的括号嵌套深度
+*/
+func (p *Parser) synchronize(depth int) {
+	p.panicking = false
+
+	for !p.curTokenIs(token.EOF) {
+		if statementKeywords[p.peekToken.Type] {
+			p.depth = depth
+			return
+		}
+		if p.depth <= depth {
+			if p.curTokenIs(token.SEMICOLON) ||
+				p.peekTokenIs(token.RBRACE) ||
+				p.peekTokenIs(token.EOF) ||
+				p.peekToken.NewlineBefore {
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
+
+/*
+	解析一串语句, 直到遇见end或EOF, 出错的语句会被丢弃
+	curToken停在end或EOF上
+*/
+func (p *Parser) parseStatements(end token.TokenType) []ast.Statement {
+	statements := []ast.Statement{}
+
+	for !p.curTokenIs(end) && !p.curTokenIs(token.EOF) && !p.tooManyErrors() {
+		depth := p.prevDepth
+		stmt := p.parseStatement()
+
+		if p.panicking {
+			p.synchronize(depth)
+		} else if stmt != nil {
+			statements = append(statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	return statements
+}
@@ -9,8 +9,9 @@ import (
 	"finger/ast"
 	"finger/lexer"
 	"finger/token"
-	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 /*
@@ -19,10 +20,17 @@ import (
 const (
 	_ int = iota
 	LOWSET // 最低优先级
+	ASSIGNMENT // = 右结合
+	COALESCE // ??
+	BITOR // |
+	BITXOR // ^
+	BITAND // &
 	EQUALS // ==
 	LESSGREATER // > or <
+	SHIFT // << or >>
 	SUM // +
 	PRODUCT // *
+	EXPONENT // **
 	PREFIX // -X or !X
 	CALL // myFunction(X)
 	INDEX // array[index] 数组索引最高优先级
@@ -31,15 +39,34 @@ const (
 
 // 优先级表
 var precedences = map[token.TokenType]int {
+	token.NULLISH: COALESCE,
 	token.EQ: EQUALS,
 	token.NOT_EQ: EQUALS,
+	token.STRICT_EQ: EQUALS,
+	token.STRICT_NOT_EQ: EQUALS,
 	token.LT: LESSGREATER,
 	token.GT: LESSGREATER,
 	token.PLUS: SUM,
 	token.MINUS: SUM,
+	token.LTE: LESSGREATER,
+	token.GTE: LESSGREATER,
+	token.INSTANCEOF: LESSGREATER,
+	token.IN: LESSGREATER,
 	token.SLASH: PRODUCT,
 	token.ASTERISK: PRODUCT,	
+	token.MODULO: PRODUCT,
+	token.POWER: EXPONENT,
+	token.BIT_AND: BITAND,
+	token.BIT_OR: BITOR,
+	token.BIT_XOR: BITXOR,
+	token.BIT_SHIFT_LEFT: SHIFT,
+	token.BIT_SHIFT_RIGHT: SHIFT,
+	token.LPAREN: CALL,
+	token.TEMPLATE: CALL,
+	token.TEMPLATE_HEAD: CALL,
 	token.LBRACKET: INDEX,
+	token.DOT: INDEX,
+	token.ASSIGN: ASSIGNMENT,
 }
 
 /*
@@ -50,7 +77,18 @@ type Parser struct {
 
 	curToken token.Token // 类似词法分析中的position, 指向当前正在解析的词法单元
 	peekToken token.Token // 类似词法分析中的readPosition, 指向当前正在解析的词法单元的下一个词法单元
-	errors []string // 错误信息, 是切片，每个错误语句都报错，而不是遇到一个错误就退出
+	errors []*ParseError // 错误信息, 是切片，每个错误语句都报错，而不是遇到一个错误就退出
+	lexerErrors int // 已经收集的词法错误数
+	panicking bool // 是否处于恐慌模式, 即出错后尚未恢复到语句边界
+
+	loopDepth int // 当前函数中包围curToken的循环层数, 用于检查break和continue
+	inGenerator bool // curToken是否直接位于生成器函数体中, 用于检查yield
+	inAsync bool // curToken是否直接位于async函数体或顶层代码中, 用于检查await
+	depth int // curToken所在的括号嵌套深度
+	prevDepth int // curToken之前的括号嵌套深度
+
+	curDoc string // 紧挨在curToken之前的文档注释
+	peekDoc string // 紧挨在peekToken之前的文档注释
 
 	prefixParseFns map[token.TokenType]prefixParseFn // 前缀解析函数 
 	infixParseFns map[token.TokenType]infixParseFn // 中缀解析函数
@@ -90,7 +128,8 @@ func New(l *lexer.Lexer) *Parser {
 	// 初始化语法分析器
 	p := &Parser{
 		l: l,
-		errors: []string{},
+		errors: []*ParseError{},
+		inAsync: true,
 		prefixParseFns: make(map[token.TokenType]prefixParseFn),
 		infixParseFns: make(map[token.TokenType]infixParseFn),
 	}
@@ -105,6 +144,10 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	// 整数字面量解析器
 	p.registerPrefix(token.NUMBER, p.parseIntegerLiteral)
+	// 浮点数字面量解析器
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	// 大整数字面量解析器
+	p.registerPrefix(token.BIGINT, p.parseBigIntLiteral)
 
 	/* 前缀表达式解析器 */
 
@@ -112,6 +155,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	// 负号表达式解析器
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	// 按位取反表达式解析器
+	p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
 
 	/* 中缀表达式解析器 */
 
@@ -127,14 +172,44 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	// 不等号表达式解析器
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
+	// 严格相等表达式解析器
+	p.registerInfix(token.STRICT_EQ, p.parseInfixExpression)
+	// 严格不等表达式解析器
+	p.registerInfix(token.STRICT_NOT_EQ, p.parseInfixExpression)
 	// 小于号表达式解析器
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	// 大于号表达式解析器
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	// 小于等于号表达式解析器
+	p.registerInfix(token.LTE, p.parseInfixExpression)
+	// 大于等于号表达式解析器
+	p.registerInfix(token.GTE, p.parseInfixExpression)
+	// 取模表达式解析器
+	p.registerInfix(token.MODULO, p.parseInfixExpression)
+	// 幂运算表达式解析器
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	// 位运算表达式解析器
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.BIT_SHIFT_RIGHT, p.parseInfixExpression)
 	// 布尔字面量解析器
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	// 假布尔字面量解析器
 	p.registerPrefix(token.FALSE, p.parseBoolean)
+	// null字面量解析器
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
+	// undefined字面量解析器
+	p.registerPrefix(token.UNDEFINED, p.parseUndefinedLiteral)
+	// typeof表达式解析器
+	p.registerPrefix(token.TYPEOF, p.parsePrefixExpression)
+	// 空值合并表达式解析器
+	p.registerInfix(token.NULLISH, p.parseInfixExpression)
+	// instanceof表达式解析器
+	p.registerInfix(token.INSTANCEOF, p.parseInfixExpression)
+	// in表达式解析器
+	p.registerInfix(token.IN, p.parseInfixExpression)
 
 	/* 分组解析器 */
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
@@ -142,16 +217,36 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	// 函数声明解析器
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.YIELD, p.parseYieldExpression)
+	p.registerPrefix(token.ASYNC, p.parseAsyncFunction)
+	p.registerPrefix(token.AWAIT, p.parseAwaitExpression)
 	// 调用表达式解析器(其实是左括号)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	// 字符串字面量解析器
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	// 模板字符串解析器
+	p.registerPrefix(token.TEMPLATE, p.parseTemplateLiteral)
+	p.registerPrefix(token.TEMPLATE_HEAD, p.parseTemplateLiteral)
+	// 带标签的模板字符串解析器
+	p.registerInfix(token.TEMPLATE, p.parseTaggedTemplate)
+	p.registerInfix(token.TEMPLATE_HEAD, p.parseTaggedTemplate)
 	// 数组字面量解析器
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	// 索引表达式解析器
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	// 哈希表字面量解析器
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	// 成员访问表达式解析器
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	// 赋值表达式解析器
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	// 类相关的表达式解析器
+	p.registerPrefix(token.CLASS, p.parseClassExpression)
+	p.registerPrefix(token.NEW, p.parseNewExpression)
+	p.registerPrefix(token.THIS, p.parseThisExpression)
+	p.registerPrefix(token.SUPER, p.parseSuperExpression)
+	// 非法词法单元, 错误已由词法分析器记录
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 
 	return p
 }
@@ -165,18 +260,31 @@ func (p *Parser) parseIdentifier() ast.Expression {
 
 
 /*
-	返回错误信息
+	非法词法单元解析器
+	词法分析器已经记录了错误, 这里不再重复报告, 直接进入恐慌模式
+*/
+func (p *Parser) parseIllegal() ast.Expression {
+	p.panicking = true
+	return nil
+}
+
+/*
+	返回错误信息, 包括词法错误, 按出现顺序排列
 */
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []*ParseError {
 	return p.errors
 }
 
 /*
-	添加错误信息
+	添加错误信息: peekToken不是可以接受的词法单元
 */
-func (p *Parser) peekErrors(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+func (p *Parser) peekErrors(expected ...token.TokenType) {
+	names := make([]string, len(expected))
+	for i, t := range expected {
+		names[i] = string(t)
+	}
+	p.addError(UnexpectedToken, p.peekToken, expected,
+		"expected next token to be %s, got %s instead", strings.Join(names, " or "), p.peekToken.Type)
 }
 
 /*
@@ -184,7 +292,27 @@ func (p *Parser) peekErrors(t token.TokenType) {
 */
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
+	p.curDoc = p.peekDoc
 	p.peekToken = p.l.NextToken()
+	p.peekDoc = ""
+
+	// 文档注释不参与语法分析, 暂存起来留给其后的let语句或函数
+	for p.peekToken.Type == token.DOC_COMMENT {
+		p.peekDoc = p.peekToken.Literal
+		p.peekToken = p.l.NextToken()
+	}
+	p.collectLexerErrors()
+
+	// 记录括号嵌套深度, 用于错误恢复
+	p.prevDepth = p.depth
+	switch p.curToken.Type {
+	case token.LPAREN, token.LBRACKET, token.LBRACE, token.TEMPLATE_HEAD:
+		p.depth++
+	case token.RPAREN, token.RBRACKET, token.RBRACE, token.TEMPLATE_TAIL:
+		if p.depth > 0 {
+			p.depth--
+		}
+	}
 }
 
 /*
@@ -199,6 +327,19 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	return false 
 }
 
+/*
+	检查peekToken是否是列表的结束符
+	不是时报告此处可以接受逗号或结束符
+*/
+func (p *Parser) expectListEnd(end token.TokenType) bool {
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return true
+	}
+	p.peekErrors(token.COMMA, end)
+	return false
+}
+
 /*
 	检查curToken是否是预期的词法单元
 */
@@ -217,15 +358,10 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 	AST解析器
 */
 func (p *Parser) ParseProgram() *ast.Program {
-	// 构造AST的根节点
+	// 构造AST的根节点, 出错时只包含解析成功的语句
 	program := &ast.Program{}
-	program.Statements = []ast.Statement{}
 	// 遍历输入的词法单元, 直到遇见EOF
-	for p.curToken.Type != token.EOF {
-		stmt := p.parseStatement()
-		program.Statements = append(program.Statements, stmt)
-		p.nextToken()
-	}
+	program.Statements = p.parseStatements(token.EOF)
 
 	return program
 }
@@ -234,14 +370,55 @@ func (p *Parser) ParseProgram() *ast.Program {
 	语句解析器
 */
 func (p *Parser) parseStatement() ast.Statement {
+	// 解析失败时返回nil, 而不是包含nil指针的接口值
 	switch p.curToken.Type {
 		case token.LET:
-			return p.parseLetStatement()
+			if stmt := p.parseLetStatement(); stmt != nil {
+				return stmt
+			}
 		case token.RETURN:
-			return p.parseReturnStatement()
+			if stmt := p.parseReturnStatement(); stmt != nil {
+				return stmt
+			}
+		case token.WHILE:
+			if stmt := p.parseWhileStatement(); stmt != nil {
+				return stmt
+			}
+		case token.FOR:
+			if stmt := p.parseForOfStatement(); stmt != nil {
+				return stmt
+			}
+		case token.BREAK:
+			if stmt := p.parseBreakStatement(); stmt != nil {
+				return stmt
+			}
+		case token.CONTINUE:
+			if stmt := p.parseContinueStatement(); stmt != nil {
+				return stmt
+			}
+		case token.THROW:
+			if stmt := p.parseThrowStatement(); stmt != nil {
+				return stmt
+			}
+		case token.TRY:
+			if stmt := p.parseTryStatement(); stmt != nil {
+				return stmt
+			}
+		case token.CLASS:
+			// 匿名类只能作为表达式
+			if !p.peekTokenIs(token.IDENT) {
+				if stmt := p.parseExpressionStatement(); stmt != nil {
+					return stmt
+				}
+			} else if stmt := p.parseClassStatement(); stmt != nil {
+				return stmt
+			}
 		default:
-			return p.parseExpressionStatement()
+			if stmt := p.parseExpressionStatement(); stmt != nil {
+				return stmt
+			}
 	}
+	return nil
 }
 
 /*
@@ -249,7 +426,7 @@ func (p *Parser) parseStatement() ast.Statement {
 */
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	// 创建一个let语句节点
-	stmt := &ast.LetStatement{Token: p.curToken}
+	stmt := &ast.LetStatement{Token: p.curToken, Doc: p.curDoc}
 
 	// 判断下一个是不是期望的词法单元, 即标识符
 	if !p.expectPeek(token.IDENT) {
@@ -267,10 +444,20 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWSET)
 
-	for !p.curTokenIs(token.SEMICOLON) {
-		p.nextToken()
+	// let f = fn() {...} 和 let C = class {...} 的文档注释同时属于这个函数或类
+	switch value := stmt.Value.(type) {
+	case *ast.FunctionLiteral:
+		if value.Doc == "" {
+			value.Doc = stmt.Doc
+		}
+	case *ast.ClassLiteral:
+		if value.Doc == "" {
+			value.Doc = stmt.Doc
+		}
 	}
 
+	p.endStatement()
+
 	return stmt
 }
 
@@ -280,13 +467,17 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
+	// return之后不能换行, 换行的return语句不带返回值
+	if p.statementCanEnd() {
+		p.endStatement()
+		return stmt
+	}
+
 	p.nextToken()
 
 	stmt.ReturnValue = p.parseExpression(LOWSET)
 
-	for !p.curTokenIs(token.SEMICOLON) {
-		p.nextToken()
-	}
+	p.endStatement()
 
 	return stmt
 }
@@ -299,11 +490,36 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	
 	stmt.Expression = p.parseExpression(LOWSET)
 
+	p.endStatement()
+
+	return stmt
+}
+
+/*
+	检查语句能否在curToken处结束
+	下一个词法单元是分号、右花括号、EOF或位于新的一行时, 语句可以结束
+*/
+func (p *Parser) statementCanEnd() bool {
+	return p.peekTokenIs(token.SEMICOLON) ||
+		p.peekTokenIs(token.RBRACE) ||
+		p.peekTokenIs(token.EOF) ||
+		p.peekToken.NewlineBefore
+}
+
+/*
+	结束一条语句, 按JavaScript的规则自动插入分号
+	有分号时前移到分号上; 在右花括号、EOF和换行之前可以省略分号
+	以右花括号结尾的语句(如if表达式)之后也可以省略分号
+*/
+func (p *Parser) endStatement() {
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
+		return
 	}
-
-	return stmt
+	if p.statementCanEnd() || p.curTokenIs(token.RBRACE) {
+		return
+	}
+	p.peekErrors(token.SEMICOLON)
 }
 
 /*
@@ -334,8 +550,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(MissingPrefixParser, p.curToken, p.expressionStarts(), "no prefix parse function for %s found", t)
 }
 
 
@@ -345,11 +560,73 @@ func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 	// 将字符串转换为int64
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	literal, base := integerLiteralBase(p.curToken.Literal)
+	value, err := strconv.ParseInt(literal, base, 64)
+
+	if err != nil {
+		p.addError(InvalidLiteral, p.curToken, nil, "could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+/*
+	大整数解析器
+*/
+func (p *Parser) parseBigIntLiteral() ast.Expression {
+	lit := &ast.BigIntLiteral{Token: p.curToken}
+
+	literal, base := integerLiteralBase(strings.TrimSuffix(p.curToken.Literal, "n"))
+	value, ok := new(big.Int).SetString(literal, base)
+
+	if !ok {
+		p.addError(InvalidLiteral, p.curToken, nil, "could not parse %q as bigint", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+/*
+	去掉整数字面量中的下划线, 并返回解析时使用的进制
+	只有带进制前缀(0x | 0o | 0b)时才按其他进制解析, 0开头的普通整数仍是十进制
+*/
+func integerLiteralBase(literal string) (string, int) {
+	literal = strings.ReplaceAll(literal, "_", "")
+	if len(literal) > 1 && literal[0] == '0' && radixPrefix(literal[1]) {
+		return literal, 0
+	}
+
+	return literal, 10
+}
+
+/*
+	检查字符是否是进制前缀
+*/
+func radixPrefix(ch byte) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+	浮点数解析器
+*/
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(p.curToken.Literal, "_", ""), 64)
 
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(InvalidLiteral, p.curToken, nil, "could not parse %q as float", p.curToken.Literal)
 		return nil
 	}
 
@@ -390,6 +667,9 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	precedence := p.curPrecedence()
 	p.nextToken()
 	// 为了获得有右关联特性，这里降低运算符的优先级
+	if expression.Operator == "**" {
+		precedence--
+	}
 	expression.Right = p.parseExpression(precedence)
 
 	return expression
@@ -428,6 +708,20 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: false}
 }
 
+/*
+	null字面量解析器
+*/
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
+/*
+	undefined字面量解析器
+*/
+func (p *Parser) parseUndefinedLiteral() ast.Expression {
+	return &ast.UndefinedLiteral{Token: p.curToken}
+}
+
 /*
 	分组解析器
 */
@@ -485,14 +779,13 @@ func (p *Parser) parseIfExpression() ast.Expression {
 */
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
-	block.Statements = []ast.Statement{}
 
 	p.nextToken()
 
-	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		block.Statements = append(block.Statements, stmt)
-		p.nextToken()
+	block.Statements = p.parseStatements(token.RBRACE)
+
+	if p.curTokenIs(token.EOF) {
+		p.addError(UnexpectedToken, p.curToken, []token.TokenType{token.RBRACE}, "expected %s before end of input", token.RBRACE)
 	}
 
 	return block
@@ -502,7 +795,48 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	函数声明解析器
 */
 func (p *Parser) parseFunctionLiteral() ast.Expression {
-	lit := &ast.FunctionLiteral{Token: p.curToken}
+	if lit := p.parseFunction(false); lit != nil {
+		return lit
+	}
+	return nil
+}
+
+/*
+	async函数解析器 async fn(params) {...}
+*/
+func (p *Parser) parseAsyncFunction() ast.Expression {
+	doc := p.curDoc
+
+	if !p.expectPeek(token.FUNCTION) {
+		return nil
+	}
+
+	lit := p.parseFunction(true)
+	if lit == nil {
+		return nil
+	}
+	if lit.Doc == "" {
+		lit.Doc = doc
+	}
+
+	return lit
+}
+
+/*
+	解析 fn 之后的函数, curToken是fn
+*/
+func (p *Parser) parseFunction(async bool) *ast.FunctionLiteral {
+	lit := &ast.FunctionLiteral{Token: p.curToken, Doc: p.curDoc, Async: async}
+
+	// fn* 声明生成器函数
+	if p.peekTokenIs(token.ASTERISK) {
+		p.nextToken()
+		if async {
+			p.addError(UnexpectedToken, p.curToken, nil, "async generators are not supported")
+			return nil
+		}
+		lit.Generator = true
+	}
 
 	if !p.expectPeek(token.LPAREN) {
 		return nil 
@@ -515,7 +849,7 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Body = p.parseBlockStatement()
+	lit.Body = p.parseFunctionBody(lit)
 
 	return lit
 }
@@ -532,7 +866,9 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifiers
 	}
 
-	p.nextToken()
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 
 	ident := &ast.Identifier{
 		Token: p.curToken,
@@ -542,7 +878,9 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 		
 		ident := &ast.Identifier{
 			Token: p.curToken,
@@ -552,7 +890,7 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		identifiers = append(identifiers, ident)
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectListEnd(token.RPAREN) {
 		return nil
 	}
 
@@ -589,13 +927,64 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 		args = append(args, p.parseExpression(LOWSET))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectListEnd(token.RPAREN) {
 		return nil
 	}
 
 	return args
 }
 
+/*
+	模板字符串解析器
+	插值中的表达式直接由普通的表达式解析器解析
+*/
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	lit := &ast.TemplateLiteral{
+		Token: p.curToken,
+		Quasis: []string{p.curToken.Literal},
+		Raws: []string{p.curToken.Raw},
+	}
+
+	if p.curTokenIs(token.TEMPLATE) {
+		return lit
+	}
+
+	for {
+		p.nextToken()
+		lit.Expressions = append(lit.Expressions, p.parseExpression(LOWSET))
+
+		if p.peekTokenIs(token.TEMPLATE_MIDDLE) {
+			p.nextToken()
+			lit.Quasis = append(lit.Quasis, p.curToken.Literal)
+			lit.Raws = append(lit.Raws, p.curToken.Raw)
+			continue
+		}
+		if !p.peekTokenIs(token.TEMPLATE_TAIL) {
+			p.peekErrors(token.TEMPLATE_MIDDLE, token.TEMPLATE_TAIL)
+			return nil
+		}
+		p.nextToken()
+		lit.Quasis = append(lit.Quasis, p.curToken.Literal)
+		lit.Raws = append(lit.Raws, p.curToken.Raw)
+		return lit
+	}
+}
+
+/*
+	带标签的模板字符串解析器, 标签位于模板字符串的左侧
+*/
+func (p *Parser) parseTaggedTemplate(tag ast.Expression) ast.Expression {
+	exp := &ast.TaggedTemplateExpression{Token: p.curToken, Tag: tag}
+
+	template, ok := p.parseTemplateLiteral().(*ast.TemplateLiteral)
+	if !ok {
+		return nil
+	}
+	exp.Template = template
+
+	return exp
+}
+
 /*
 	字符串字面量解析器
 */
@@ -626,7 +1015,7 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 		list = append(list, p.parseExpression(LOWSET))
 	}
 
-	if !p.expectPeek(end) {
+	if !p.expectListEnd(end) {
 		return nil
 	}
 
@@ -647,24 +1036,63 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+/*
+	成员访问表达式解析器, 点号之后的属性名可以是关键字, 如 obj.default
+*/
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+
+	if !p.peekTokenIs(token.IDENT) && !token.IsKeyword(p.peekToken.Literal) {
+		p.peekErrors(token.IDENT)
+		return nil
+	}
+	p.nextToken()
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+/*
+	赋值表达式解析器
+	赋值是右结合的, a = b = 1 等价于 a = (b = 1)
+*/
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{Token: p.curToken, Target: target}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.MemberExpression, *ast.IndexExpression:
+	case nil:
+		// 左侧解析失败, 错误已经记录
+		return nil
+	default:
+		p.addError(UnexpectedToken, p.curToken, nil, "invalid assignment target %s", target.String())
+		return nil
+	}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(ASSIGNMENT - 1)
+
+	return exp
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	hash.Properties = []*ast.HashProperty{}
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
-		key := p.parseExpression(LOWSET)
 
-		if !p.expectPeek(token.COLON) {
+		prop := p.parseHashProperty()
+		if prop == nil {
 			return nil
 		}
 
-		p.nextToken()
-		value := p.parseExpression(LOWSET)
-
-		hash.Pairs[key] = value
+		hash.Properties = append(hash.Properties, prop)
 
-		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RBRACE) {
+			p.peekErrors(token.COMMA, token.RBRACE)
 			return nil
 		}
 	}
@@ -674,4 +1102,143 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	}
 
 	return hash
-}
\ No newline at end of file
+}
+
+/*
+	哈希表属性解析器
+	支持 key: value | name | [expr]: value | name() {...} | ...other
+*/
+func (p *Parser) parseHashProperty() *ast.HashProperty {
+	prop := &ast.HashProperty{Kind: ast.PropertyKeyValue}
+
+	// async方法 async name() {...}, 而 {async: 1} 和 {async() {...}} 中的async是属性名
+	async := false
+	if p.curTokenIs(token.ASYNC) && !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.LPAREN) &&
+		!p.peekTokenIs(token.COMMA) && !p.peekTokenIs(token.RBRACE) {
+		async = true
+		p.nextToken()
+	}
+
+	// 生成器方法 *name() {...}
+	generator := false
+	if p.curTokenIs(token.ASTERISK) {
+		if async {
+			p.addError(UnexpectedToken, p.curToken, nil, "async generators are not supported")
+			return nil
+		}
+		generator = true
+		p.nextToken()
+	}
+
+	switch {
+	// 展开属性 ...other
+	case p.curTokenIs(token.SPREAD):
+		prop.Kind = ast.PropertySpread
+		p.nextToken()
+		prop.Value = p.parseExpression(LOWSET)
+		if prop.Value == nil {
+			return nil
+		}
+		return prop
+	// 计算属性名 [expr]
+	case p.curTokenIs(token.LBRACKET):
+		prop.Computed = true
+		p.nextToken()
+		prop.Key = p.parseExpression(LOWSET)
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+	// 标识符属性名, 可能是简写属性
+	case p.curTokenIsPropertyName():
+		prop.Key = p.parseIdentifier()
+		if !generator && !async && p.curTokenIs(token.IDENT) && (p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.RBRACE)) {
+			prop.Kind = ast.PropertyShorthand
+			prop.Value = prop.Key
+			return prop
+		}
+	default:
+		prop.Key = p.parseExpression(LOWSET)
+	}
+
+	if prop.Key == nil {
+		return nil
+	}
+
+	// 方法简写 name(params) {...}
+	if p.peekTokenIs(token.LPAREN) {
+		prop.Kind = ast.PropertyMethod
+		method := p.parseMethodLiteral(generator, async)
+		if method == nil {
+			return nil
+		}
+		prop.Value = method
+		return prop
+	}
+
+	if generator || async {
+		p.peekErrors(token.LPAREN)
+		return nil
+	}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	prop.Value = p.parseExpression(LOWSET)
+	if prop.Value == nil {
+		return nil
+	}
+
+	return prop
+}
+
+/*
+	检查当前词法单元能否作为属性名
+	关键字在属性名的位置上按普通名字处理, 如 {if: 1, default() {...}}
+	true, false, null, undefined 仍然是字面量
+*/
+func (p *Parser) curTokenIsPropertyName() bool {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return true
+	case token.TRUE, token.FALSE, token.NULL, token.UNDEFINED:
+		return false
+	}
+
+	return token.IsKeyword(p.curToken.Literal) && (p.peekTokenIs(token.COLON) || p.peekTokenIs(token.LPAREN))
+}
+
+/*
+	方法简写解析器, 解析属性名之后的 (params) {...} 部分
+*/
+func (p *Parser) parseMethodLiteral(generator, async bool) *ast.FunctionLiteral {
+	lit := &ast.FunctionLiteral{Token: token.Token{Type: token.FUNCTION, Literal: "fn"}, Doc: p.curDoc, Generator: generator, Async: async}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lit.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	lit.Body = p.parseFunctionBody(lit)
+
+	return lit
+}
+
+/*
+	函数体解析器, 函数体外的循环不能被函数体中的break和continue跳出
+	yield只能直接出现在生成器函数体中, await只能直接出现在async函数体中, 都不能出现在其中嵌套的普通函数里
+*/
+func (p *Parser) parseFunctionBody(lit *ast.FunctionLiteral) *ast.BlockStatement {
+	loopDepth, inGenerator, inAsync := p.loopDepth, p.inGenerator, p.inAsync
+	p.loopDepth, p.inGenerator, p.inAsync = 0, lit.Generator, lit.Async
+	body := p.parseBlockStatement()
+	p.loopDepth, p.inGenerator, p.inAsync = loopDepth, inGenerator, inAsync
+
+	return body
+}
@@ -3,6 +3,8 @@ package parser
 import (
 	"finger/ast"
 	"finger/lexer"
+	"finger/token"
+	"strings"
 	"testing"
 )
 
@@ -41,6 +43,29 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestCallExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"sum(1, 2)", "sum(1, 2)"},
+		{"a + sum(b * c) + d", "((a + sum((b * c))) + d)"},
+		{"h[\"f\"](1)", "(h[f])(1)"},
+		{"f(1)(2)", "f(1)(2)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	// 检查语句是否是let语句
 	if s.TokenLiteral() != "let" {
@@ -82,3 +107,691 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestParsingEnhancedHashLiteral(t *testing.T) {
+	input := `{name, [prefix + "id"]: 1, greet(who) { who }, ...defaults, age: 3}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	tests := []struct {
+		kind     ast.PropertyKind
+		computed bool
+		str      string
+	}{
+		{ast.PropertyShorthand, false, "name"},
+		{ast.PropertyKeyValue, true, "[(prefix + id)]:1"},
+		{ast.PropertyMethod, false, "greet(who) {who}"},
+		{ast.PropertySpread, false, "...defaults"},
+		{ast.PropertyKeyValue, false, "age:3"},
+	}
+
+	if len(hash.Properties) != len(tests) {
+		t.Fatalf("hash.Properties has wrong length. got=%d", len(hash.Properties))
+	}
+
+	for i, tt := range tests {
+		prop := hash.Properties[i]
+		if prop.Kind != tt.kind {
+			t.Errorf("properties[%d] - kind wrong. expected=%d, got=%d", i, tt.kind, prop.Kind)
+		}
+		if prop.Computed != tt.computed {
+			t.Errorf("properties[%d] - computed wrong. expected=%t, got=%t", i, tt.computed, prop.Computed)
+		}
+		if prop.String() != tt.str {
+			t.Errorf("properties[%d] - String() wrong. expected=%q, got=%q", i, tt.str, prop.String())
+		}
+	}
+}
+
+func TestNullishAndTypeofPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a ?? b == c", "(a ?? (b == c))"},
+		{"typeof a == b", "((typeof a) == b)"},
+		{"a ?? null ?? undefined", "((a ?? null) ?? undefined)"},
+		{"typeof a in b", "((typeof a) in b)"},
+		{`"k" in h == true`, "((k in h) == true)"},
+		{"i + 1 in xs", "((i + 1) in xs)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestLexerErrorsAreReported(t *testing.T) {
+	l := lexer.New("let s = \"abc\n;")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got=%d: %q", len(errors), errors)
+	}
+	if errors[0].Error() != "unterminated string literal at 1:9" {
+		t.Errorf("wrong error. got=%q", errors[0].Error())
+	}
+}
+
+func TestParsingTemplateLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"`hello`", "`hello`"},
+		{"`a${x + 1}b${y}`", "`a${(x + 1)}b${y}`"},
+		{"tag`a${x}`", "tag`a${x}`"},
+		{"obj[tag]`x` + 1", "((obj[tag])`x` + 1)"},
+		{"`a\\`b\\${c}${d}`", "`a\\`b\\${c}${d}`"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	input := `
+	/** Adds two numbers. */
+	let sum = fn(a, b) { a + b };
+	/** The answer. */
+	let answer = 42;
+	/** Dropped: not followed by a declaration. */
+	answer;
+	let plain = /** Inline doc. */ fn() { 1 };
+	`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program.Statements does not contain 4 statements. got=%d", len(program.Statements))
+	}
+
+	sum := program.Statements[0].(*ast.LetStatement)
+	if sum.Doc != "Adds two numbers." {
+		t.Errorf("sum.Doc wrong. got=%q", sum.Doc)
+	}
+	if fn := sum.Value.(*ast.FunctionLiteral); fn.Doc != "Adds two numbers." {
+		t.Errorf("function doc wrong. got=%q", fn.Doc)
+	}
+
+	answer := program.Statements[1].(*ast.LetStatement)
+	if answer.Doc != "The answer." {
+		t.Errorf("answer.Doc wrong. got=%q", answer.Doc)
+	}
+
+	plain := program.Statements[3].(*ast.LetStatement)
+	if plain.Doc != "" {
+		t.Errorf("plain.Doc should be empty. got=%q", plain.Doc)
+	}
+	if fn := plain.Value.(*ast.FunctionLiteral); fn.Doc != "Inline doc." {
+		t.Errorf("inline function doc wrong. got=%q", fn.Doc)
+	}
+}
+
+func TestKeywordsAsPropertyNames(t *testing.T) {
+	input := `{if: 1, default() { 2 }, get: 3, delete}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	hash := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral)
+
+	tests := []struct {
+		kind ast.PropertyKind
+		key  string
+	}{
+		{ast.PropertyKeyValue, "if"},
+		{ast.PropertyMethod, "default"},
+		{ast.PropertyKeyValue, "get"},
+		{ast.PropertyShorthand, "delete"},
+	}
+
+	if len(hash.Properties) != len(tests) {
+		t.Fatalf("hash.Properties has wrong length. got=%d", len(hash.Properties))
+	}
+
+	for i, tt := range tests {
+		prop := hash.Properties[i]
+		if prop.Kind != tt.kind {
+			t.Errorf("properties[%d] - kind wrong. expected=%d, got=%d", i, tt.kind, prop.Kind)
+		}
+		ident, ok := prop.Key.(*ast.Identifier)
+		if !ok || ident.Value != tt.key {
+			t.Errorf("properties[%d] - key wrong. expected=%q, got=%s", i, tt.key, prop.Key)
+		}
+	}
+
+	l = lexer.New(`{if}`)
+	p = New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected a parse error for a keyword used as a shorthand property")
+	}
+}
+
+func TestAutomaticSemicolonInsertion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 5\nlet y = x\ny", []string{"let x = 5;", "let y = x;", "y"}},
+		{"let a = 1", []string{"let a = 1;"}},
+		{"a\n+ b", []string{"(a + b)"}},
+		{"x /* two\nlines */ y", []string{"x", "y"}},
+		{"if (x) { 1 } 2", []string{"ifx 1", "2"}},
+		{"fn() { return\n1 }", []string{"fn() {\nreturn ;1\n}"}},
+		{"fn() { return 1 }", []string{"fn() {\nreturn 1;\n}"}},
+		{"return", []string{"return ;"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != len(tt.expected) {
+			t.Fatalf("%q: expected %d statements, got=%d", tt.input, len(tt.expected), len(program.Statements))
+		}
+		for i, expected := range tt.expected {
+			if program.Statements[i].String() != expected {
+				t.Errorf("%q: statements[%d] expected=%q, got=%q", tt.input, i, expected, program.Statements[i].String())
+			}
+		}
+	}
+}
+
+func TestMissingSemicolon(t *testing.T) {
+	tests := []string{"1 2", "let a = 1 let b = 2", "return 1 2"}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a missing semicolon error", input)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	input := `
+let a = ;
+let b = 2;
+let c = (1 + ;
+let d = 4;
+let f = fn() { let x = {k: 1 2}; x }
+let g = 7
+`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	expectedErrors := []string{
+		"no prefix parse function for ; found at 2:9",
+		"no prefix parse function for ; found at 4:14",
+		"expected next token to be , or }, got number instead at 6:30",
+	}
+	errors := p.Errors()
+	if len(errors) != len(expectedErrors) {
+		t.Fatalf("expected %d errors, got=%d: %q", len(expectedErrors), len(errors), errors)
+	}
+	for i, expected := range expectedErrors {
+		if errors[i].Error() != expected {
+			t.Errorf("errors[%d] expected=%q, got=%q", i, expected, errors[i].Error())
+		}
+	}
+
+	// 出错的语句被丢弃, 其余语句保留在部分AST中, 函数体内只丢弃出错的那条语句
+	expectedStatements := []string{"let b = 2;", "let d = 4;", "let f = fn() {\nx\n};", "let g = 7;"}
+	if len(program.Statements) != len(expectedStatements) {
+		t.Fatalf("expected %d statements, got=%d: %q", len(expectedStatements), len(program.Statements), program.String())
+	}
+	for i, expected := range expectedStatements {
+		if program.Statements[i].String() != expected {
+			t.Errorf("statements[%d] expected=%q, got=%q", i, expected, program.Statements[i].String())
+		}
+	}
+}
+
+func TestStructuredParseErrors(t *testing.T) {
+	l := lexer.New(`let = 1; let s = "open`)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 2 {
+		t.Fatalf("expected 2 errors, got=%d: %v", len(errors), errors)
+	}
+
+	first := errors[0]
+	if first.Span.Start.Line != 1 || first.Span.Start.Column != 5 {
+		t.Errorf("wrong position. got=%s", first.Span.Start)
+	}
+	if first.Found.Type != token.ASSIGN {
+		t.Errorf("wrong found token. got=%q", first.Found.Type)
+	}
+	if len(first.Expected) != 1 || first.Expected[0] != token.IDENT {
+		t.Errorf("wrong expected tokens. got=%q", first.Expected)
+	}
+	if first.Kind != UnexpectedToken {
+		t.Errorf("wrong kind. got=%s", first.Kind)
+	}
+	if first.Span.End.Column != 6 {
+		t.Errorf("wrong span end. got=%s", first.Span.End)
+	}
+	if errors[1].Kind != IllegalToken || errors[1].Span.End.Column != 23 {
+		t.Errorf("wrong lexer error kind or span. got=%s ending at %s", errors[1].Kind, errors[1].Span.End)
+	}
+
+	if errors[1].Error() != "unterminated string literal at 1:18" {
+		t.Errorf("wrong lexer error. got=%q", errors[1].Error())
+	}
+}
+
+func TestTruncatedInputTerminates(t *testing.T) {
+	tests := []string{
+		"let", "let x", "let x =", "fn(", "fn(x", "fn(x) {", "fn(1) {}",
+		"[1, 2", "{a: ", "`a${", "if (x) {", "f(1,", "(((",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected errors", input)
+		}
+	}
+}
+
+func TestTooManyErrors(t *testing.T) {
+	l := lexer.New(strings.Repeat(")\n", MaxErrors*2))
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != MaxErrors {
+		t.Fatalf("expected %d errors, got=%d", MaxErrors, len(errors))
+	}
+	if errors[MaxErrors-1].Kind != TooManyErrors {
+		t.Errorf("last error should report too many errors. got=%q", errors[MaxErrors-1].Error())
+	}
+}
+
+func TestParseErrorKinds(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     ErrorKind
+		expected []token.TokenType
+		message  string
+	}{
+		{"99999999999999999999", InvalidLiteral, nil, `could not parse "99999999999999999999" as integer`},
+		{"[1, 2 3]", UnexpectedToken, []token.TokenType{token.COMMA, token.RBRACKET}, "expected next token to be , or ], got number instead"},
+		{"{a: 1 b}", UnexpectedToken, []token.TokenType{token.COMMA, token.RBRACE}, "expected next token to be , or }, got IDENT instead"},
+		{"f(1 2)", UnexpectedToken, []token.TokenType{token.COMMA, token.RPAREN}, "expected next token to be , or ), got number instead"},
+		{"1 @", IllegalToken, nil, "unexpected character '@'"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d: %v", tt.input, len(errors), errors)
+		}
+		err := errors[0]
+		if err.Kind != tt.kind {
+			t.Errorf("%q: kind wrong. expected=%s, got=%s", tt.input, tt.kind, err.Kind)
+		}
+		if err.Message != tt.message {
+			t.Errorf("%q: message wrong. expected=%q, got=%q", tt.input, tt.message, err.Message)
+		}
+		if len(err.Expected) != len(tt.expected) {
+			t.Errorf("%q: expected tokens wrong. expected=%q, got=%q", tt.input, tt.expected, err.Expected)
+			continue
+		}
+		for i := range tt.expected {
+			if err.Expected[i] != tt.expected[i] {
+				t.Errorf("%q: expected tokens wrong. expected=%q, got=%q", tt.input, tt.expected, err.Expected)
+			}
+		}
+	}
+
+	// 缺少前缀解析函数时, 可以接受的是所有能开始表达式的词法单元
+	p := New(lexer.New(")"))
+	p.ParseProgram()
+	err := p.Errors()[0]
+	if err.Kind != MissingPrefixParser {
+		t.Fatalf("kind wrong. got=%s", err.Kind)
+	}
+	starts := map[token.TokenType]bool{}
+	for _, t := range err.Expected {
+		starts[t] = true
+	}
+	if !starts[token.IDENT] || !starts[token.LPAREN] || starts[token.RPAREN] || starts[token.ILLEGAL] {
+		t.Errorf("wrong expression starts. got=%q", err.Expected)
+	}
+}
+
+func TestMemberAndAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a.b.c", "((a.b).c)"},
+		{"a.b(1)", "(a.b)(1)"},
+		{"a.default", "(a.default)"},
+		{"a = b = 1", "(a = (b = 1))"},
+		{"a.b = 1 + 2", "((a.b) = (1 + 2))"},
+		{"a[0] = x ?? y", "((a[0]) = (x ?? y))"},
+		{"new Point(1, 2).x", "(new Point(1, 2).x)"},
+		{"new a.B", "new (a.B)()"},
+		{"this.x = super.x", "((this.x) = (super.x))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	for _, input := range []string{"1 = 2", "a + b = 1", "f() = 1"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected an invalid assignment target error", input)
+		}
+	}
+}
+
+func TestClassStatement(t *testing.T) {
+	input := `
+/** A point. */
+class Point extends Base {
+	x = 0
+	static origin
+	constructor(x) { super(); this.x = x }
+	norm() { this.x }
+	static create(x) { new Point(x) }
+}
+let Anon = class { y = 1 }
+`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements has wrong length. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ClassStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.ClassStatement. got=%T", program.Statements[0])
+	}
+	class := stmt.Class
+	if class.Name.Value != "Point" || class.SuperClass.String() != "Base" || class.Doc != "A point." {
+		t.Errorf("wrong class header. got name=%s extends=%s doc=%q", class.Name, class.SuperClass, class.Doc)
+	}
+	if class.Constructor == nil || len(class.Constructor.Parameters) != 1 {
+		t.Fatalf("constructor not parsed. got=%v", class.Constructor)
+	}
+
+	tests := []struct {
+		name   string
+		static bool
+		method bool
+	}{
+		{"x", false, false},
+		{"origin", true, false},
+		{"norm", false, true},
+		{"create", true, true},
+	}
+
+	if len(class.Members) != len(tests) {
+		t.Fatalf("class.Members has wrong length. got=%d", len(class.Members))
+	}
+
+	for i, tt := range tests {
+		member := class.Members[i]
+		if member.Name.Value != tt.name || member.Static != tt.static || member.Method != tt.method {
+			t.Errorf("members[%d] wrong. expected=%+v, got=%s", i, tt, member)
+		}
+	}
+
+	let := program.Statements[1].(*ast.LetStatement)
+	if _, ok := let.Value.(*ast.ClassLiteral); !ok {
+		t.Errorf("let value is not *ast.ClassLiteral. got=%T", let.Value)
+	}
+
+	for _, input := range []string{
+		"class A { constructor() {} constructor() {} }",
+		"class A { 1 }",
+		"class A {",
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parse error", input)
+		}
+	}
+}
+
+func TestInstanceofAndProto(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a instanceof B == true", "((a instanceof B) == true)"},
+		{"a.b instanceof C.D", "((a.b) instanceof (C.D))"},
+		{"x + 1 instanceof y", "((x + 1) instanceof y)"},
+		{"{__proto__: base, x: 1}", "{__proto__:base, x:1}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestGeneratorsAndForOf(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let g = fn*() { yield 1 }", "let g = fn*() {\n(yield 1)\n};"},
+		{"fn*(x) { let y = yield; yield* x }", "fn*(x) {\nlet y = (yield);(yield* x)\n}"},
+		{"fn*() { f(yield 1, 2) }", "fn*() {\nf((yield 1), 2)\n}"},
+		{"fn*() { yield a + b }", "fn*() {\n(yield (a + b))\n}"},
+		{"for (let x of xs) { print(x) }", "for (let x of xs) {print(x)}"},
+		{"for (x of range(3)) { break }", "for (x of range(3)) {break;}"},
+		{"class C { *items() { yield 1 } }", "class C {*items() {(yield 1)}}"},
+		{"class C { static *[Symbol.iterator]() { yield 1 } }", "class C {static *[(Symbol.iterator)]() {(yield 1)}}"},
+		{"class C { [name](x) { x } }", "class C {[name](x) {x}}"},
+		{"let h = {*gen() { yield 1 }, [k]() { 2 }}", "let h = {*gen() {(yield 1)}, [k]() {2}};"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"yield 1", "yield outside of a generator function"},
+		{"fn*() { fn() { yield 1 } }", "yield outside of a generator function"},
+		{"for (let x in xs) {}", "expected of after the loop variable, got in instead"},
+		{"for (1 of xs) {}", "expected next token to be IDENT, got number instead"},
+		{"class C { [k] = 1 }", "computed names are only supported for methods"},
+		{"class C { *constructor() {} }", "constructor cannot be a generator"},
+		{"class C { *x = 1 }", "expected next token to be (, got = instead"},
+		{"let h = {*a: 1}", "expected next token to be (, got : instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Errorf("%q: expected a parse error", tt.input)
+			continue
+		}
+		if errs[0].Message != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errs[0].Message)
+		}
+	}
+}
+
+func TestAsyncAndAwait(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = async fn(x) { await x }", "let f = async fn(x) {\n(await x)\n};"},
+		{"await a + b", "((await a) + b)"},
+		{"await f(1)", "(await f(1))"},
+		{"class C { async load() { await 1 } static async make() {} }", "class C {async load() {(await 1)}; static async make() {}}"},
+		{"let h = {async get() { await 1 }, async: 2}", "let h = {async get() {(await 1)}, async:2};"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"fn() { await 1 }", "await is only valid in async functions and at the top level"},
+		{"async fn() { fn() { await 1 } }", "await is only valid in async functions and at the top level"},
+		{"async fn*() {}", "async generators are not supported"},
+		{"class C { async constructor() {} }", "constructor cannot be async"},
+		{"async 1", "expected next token to be function, got number instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Errorf("%q: expected a parse error", tt.input)
+			continue
+		}
+		if errs[0].Message != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errs[0].Message)
+		}
+	}
+}
+
+func TestLoopAndExceptionStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 3) { x = x + 1 }", "while ((x < 3)) {(x = (x + 1))}"},
+		{"while (true) { if (a) { break }; continue }", "while (true) {ifa break;continue;}"},
+		{"throw Error(1)", "throw Error(1);"},
+		{"try { a } catch (e) { b } finally { c }", "try {a} catch (e) {b} finally {c}"},
+		{"try { a } catch { b }", "try {a} catch {b}"},
+		{"try { a }\nfinally { c }", "try {a} finally {c}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"break", "break outside of a loop"},
+		{"while (x) { fn() { continue } }", "continue outside of a loop"},
+		{"throw\n1", "expected an expression on the same line after throw"},
+		{"try { a }", "expected next token to be catch or finally, got EOF instead"},
+		{"try { a } catch (1) {}", "expected next token to be IDENT, got number instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Errorf("%q: expected a parse error", tt.input)
+			continue
+		}
+		if errs[0].Message != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errs[0].Message)
+		}
+	}
+}
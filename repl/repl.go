@@ -8,6 +8,7 @@ import (
 	"finger/parser"
 	"fmt"
 	"io"
+	"strings"
 )
 
 const PROMPT = ">> "
@@ -17,8 +18,12 @@ const FINGER_MODEL = `
 	Hello, I'm the Finger programming language!
 	
 	Here are some commands:
+		:doc <expr>    show the documentation comment of a value
 `
 
+// 查看文档注释的命令前缀
+const DOC_COMMAND = ":doc "
+
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
@@ -31,6 +36,11 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		line := scanner.Text()
+		if strings.HasPrefix(line, DOC_COMMAND) {
+			printDoc(out, strings.TrimPrefix(line, DOC_COMMAND), env)
+			continue
+		}
+
 		l := lexer.New(line)
 		p := parser.New(l)
 
@@ -46,15 +56,51 @@ func Start(in io.Reader, out io.Writer) {
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
-		}	
+		}
+		// 在函数中出错时打印调用栈
+		if err, ok := evaluated.(*object.Error); ok && len(err.Stack) > 0 {
+			io.WriteString(out, err.StackTrace()+"\n")
+		}
+
+		// 每次输入之后运行事件循环, 执行这次输入安排的异步任务
+		if err := evaluator.RunEventLoop(); err != nil {
+			io.WriteString(out, err.Inspect()+"\n")
+		}
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
+func printParserErrors(out io.Writer, errors []*parser.ParseError) {
 	io.WriteString(out, FINGER_MODEL)
 	io.WriteString(out, "Woops! We ran into some finger erros:\n")
 	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, err := range errors {
+		io.WriteString(out, "\t"+err.Error()+"\n")
+	}
+}
+
+/*
+	对表达式求值并打印其文档注释
+*/
+func printDoc(out io.Writer, input string, env *object.Environment) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(out, p.Errors())
+		return
+	}
+
+	evaluated := evaluator.Eval(program, env)
+	if evaluated == nil {
+		return
+	}
+	if evaluated.Type() == object.ERROR_OBJ {
+		io.WriteString(out, evaluated.Inspect()+"\n")
+		return
+	}
+
+	if doc, ok := evaluator.Doc(evaluated); ok {
+		io.WriteString(out, doc+"\n")
+	} else {
+		io.WriteString(out, "no documentation for "+strings.TrimSpace(input)+"\n")
 	}
 }
@@ -1,10 +1,40 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 type Token struct {
-	Type    TokenType // 词法单元类型
-	Literal string    // 词法单元字面量
+	Type          TokenType // 词法单元类型
+	Literal       string    // 词法单元字面量
+	Raw           string    // 模板字符串片段在源码中的原始文本, 其他词法单元为空
+	Pos           Position  // 词法单元在源码中的起始位置
+	End           Position  // 词法单元结束之后的位置
+	NewlineBefore bool      // 与上一个词法单元之间是否有换行, 用于自动分号插入
+}
+
+/*
+	源码中的位置, 行号和列号从1开始, 列号按字符计算
+	零值表示位置未知
+*/
+type Position struct {
+	Offset int // 字节偏移量
+	Line   int
+	Column int
+}
+
+/*
+	检查位置是否有效
+*/
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 /* 词法单元类型 */
@@ -12,6 +42,13 @@ const (
 	ILLEGAL = "ILLEGAL" // 非法单元
 	EOF     = "EOF"     // 文件结束
 	IDENT   = "IDENT"   // 标识符
+	FLOAT   = "FLOAT"   // 浮点数字面量
+
+	// 模板字符串, 以${...}插值为界分成若干段
+	TEMPLATE        = "TEMPLATE"        // `text` 不含插值的模板
+	TEMPLATE_HEAD   = "TEMPLATE_HEAD"   // `text${
+	TEMPLATE_MIDDLE = "TEMPLATE_MIDDLE" // }text${
+	TEMPLATE_TAIL   = "TEMPLATE_TAIL"   // }text`
 
 	/* 运算符 */
 
@@ -30,6 +67,7 @@ const (
 	ASTERISK  = "*"
 	SLASH     = "/"
 	MODULO    = "%"
+	POWER     = "**"
 	INCREMENT = "++"
 	DECREMENT = "--"
 
@@ -131,6 +169,12 @@ const (
 	THIS       = "this"
 	NEW        = "new"
 
+	// 类
+	CLASS   = "class"
+	EXTENDS = "extends"
+	SUPER   = "super"
+	STATIC  = "static"
+
 	// 异步支持
 	ASYNC       = "async"
 	AWAIT       = "await"
@@ -163,13 +207,9 @@ const (
 	LEN   = "len"
 
 	// 注释
-	COMMENT = "//"
-	// @todo: 支持多行注释
-	// MULTI_LINE  = "/*"
-	// MULTI_LINE2 = "*/"
-	// DOC_COMMENT = "/**"
-	// DOC_LINE    = "*"
-	// DOC_LINE2   = "*/"
+	// 普通注释由词法分析器直接跳过, 只有文档注释会作为词法单元返回
+	COMMENT     = "//"
+	DOC_COMMENT = "DOC_COMMENT" // /** ... */ 字面量为整理后的注释正文
 
 	// 生成器
 	YIELD     = "yield"
@@ -195,6 +235,7 @@ const (
 
 /*
 检查字符串是否是关键字
+上下文关键字不在此列, 词法分析器把它们当作普通标识符返回
 */
 func LookupIdent(ident string) TokenType {
 	if tok, ok := keywords[ident]; ok {
@@ -205,6 +246,23 @@ func LookupIdent(ident string) TokenType {
 	return IDENT
 }
 
+/*
+检查字符串是否是保留的关键字
+关键字可以出现在属性名的位置上, 如 {if: 1} 和 obj.default
+*/
+func IsKeyword(ident string) bool {
+	_, ok := keywords[ident]
+	return ok
+}
+
+/*
+检查标识符词法单元是否是指定的上下文关键字, 如 for (x of xs) 中的 of
+上下文关键字只在需要它的语法位置上由语法分析器识别
+*/
+func IsContextual(tok Token, t TokenType) bool {
+	return tok.Type == IDENT && contextualKeywords[tok.Literal] == t
+}
+
 /* 关键字 */
 var keywords = map[string]TokenType{
 	// 变量声明
@@ -240,20 +298,50 @@ var keywords = map[string]TokenType{
 	// 模块系统
 	"import": IMPORT,
 	"export": EXPORT,
-	"from":   FROM,
 
 	// 原型系统
 	"typeof":     TYPEOF,
 	"__proto__":  PROTO,
-	"create":     CREATE,
 	"in":         IN,
 	"instanceof": INSTANCEOF,
 	"new":        NEW,
 	"this":       THIS,
 
+	// 类
+	"class":   CLASS,
+	"extends": EXTENDS,
+	"super":   SUPER,
+
+	// 异步支持
+	"async": ASYNC,
+	"await": AWAIT,
+
+	// 基础值
+	"true":      TRUE,
+	"false":     FALSE,
+	"null":      NULL,
+	"undefined": UNDEFINED,
+}
+
+/*
+上下文关键字
+它们在其余位置都是普通标识符, 因此 let map = {} 和 print(x) 都能正常解析
+*/
+var contextualKeywords = map[string]TokenType{
+	// 模块系统
+	"from": FROM,
+	"as":   AS,
+
+	// 迭代器
+	"of": OF,
+
+	// 原型系统
+	"create": CREATE,
+
+	// 类
+	"static": STATIC,
+
 	// 异步支持
-	"async":      ASYNC,
-	"await":      AWAIT,
 	"Promise":    PROMISE,
 	"then":       THEN,
 	"resolve":    RESOLVE,
@@ -275,21 +363,6 @@ var keywords = map[string]TokenType{
 	"get":    MAP_GET,
 	"set":    MAP_SET,
 
-	// 基础值
-	"true":      TRUE,
-	"false":     FALSE,
-	"null":      NULL,
-	"undefined": UNDEFINED,
-
-	// 数据类型
-	"boolean": BOOLEAN,
-	"number":  NUMBER,
-	"string":  STRING,
-	"array":   ARRAY,
-	"object":  OBJECT,
-	"symbol":  SYMBOL,
-	"bigint":  BIGINT,
-
 	// 函数式
 	"map":     MAPFn,
 	"reduce":  REDUCE,
@@ -299,7 +372,4 @@ var keywords = map[string]TokenType{
 	"slice":   SLICE,
 	"split":   SPLIT,
 	"join":    JOIN,
-
-	// 新增
-	"of": OF,
 }